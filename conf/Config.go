@@ -1,6 +1,7 @@
 package conf
 
 import (
+	"bot/entity"
 	"bot/entity/telegram"
 	"encoding/json"
 	"log"
@@ -22,6 +23,32 @@ type Config struct {
 	WriteRange               string `json:"write_range"`
 	KeyFile                  string `json:"key_file"`
 	RecipientsFile           string `json:"recipients_file"`
+	HolidaysFile             string `json:"holidays_file"`
+	XauHistoryRange          string `json:"xau_history_range"`
+
+	BotUsername             string   `json:"bot_username"`
+	CommandLanguages        []string `json:"command_languages"`
+	CommandDescriptionsFile string   `json:"command_descriptions_file"`
+	ParseMode               string   `json:"parse_mode"`
+
+	UpdatesMode             string `json:"updates_mode"`
+	PollingTimeoutSeconds   int    `json:"polling_timeout_seconds"`
+	WebhookUrl              string `json:"webhook_url"`
+	WebhookSecretToken      string `json:"webhook_secret_token"`
+	WebhookMaxConnections   int    `json:"webhook_max_connections"`
+	DeleteWebhookOnShutdown bool   `json:"delete_webhook_on_shutdown"`
+	UpdateDedupMinutes      int    `json:"update_dedup_minutes"`
+
+	CalendarProvider       string `json:"calendar_provider"`
+	CalendarSource         string `json:"calendar_source"`
+	CalendarTimezone       string `json:"calendar_timezone"`
+	CalendarRefreshMinutes int    `json:"calendar_refresh_minutes"`
+	CalendarCacheFile      string `json:"calendar_cache_file"`
+	HistoryFile            string `json:"history_file"`
+	SurprisePollMinutes    int    `json:"surprise_poll_minutes"`
+	SurpriseTimeoutMinutes int    `json:"surprise_timeout_minutes"`
+	ReminderMinutes        []int  `json:"reminder_minutes"`
+	WeeklyOutlookTime      string `json:"weekly_outlook_time"`
 }
 
 func Load() (Config, error) {
@@ -41,6 +68,9 @@ func Load() (Config, error) {
 func LoadRecipients(pathFile string) ([]telegram.Recipient, error) {
 	var arr []telegram.Recipient
 	recipientsFile, err := os.Open(pathFile)
+	if err != nil {
+		return nil, err
+	}
 	defer func(configFile *os.File) {
 		err := configFile.Close()
 		if err != nil {
@@ -51,3 +81,52 @@ func LoadRecipients(pathFile string) ([]telegram.Recipient, error) {
 	err = jsonParser.Decode(&arr)
 	return arr, err
 }
+
+// LoadHolidays reads the market holidays by market code (US, UK, EU, JP).
+func LoadHolidays(pathFile string) (map[string][]entity.Holiday, error) {
+	var holidays map[string][]entity.Holiday
+	holidaysFile, err := os.Open(pathFile)
+	if err != nil {
+		return nil, err
+	}
+	defer func(holidaysFile *os.File) {
+		err := holidaysFile.Close()
+		if err != nil {
+			log.Printf("could not decode json holidays %s\n", err.Error())
+		}
+	}(holidaysFile)
+	jsonParser := json.NewDecoder(holidaysFile)
+	err = jsonParser.Decode(&holidays)
+	return holidays, err
+}
+
+// LoadCommandDescriptions reads the translated descriptions of the bot commands, by language then command name.
+func LoadCommandDescriptions(pathFile string) (map[string]map[string]string, error) {
+	var descriptions map[string]map[string]string
+	descriptionsFile, err := os.Open(pathFile)
+	if err != nil {
+		return nil, err
+	}
+	defer func(descriptionsFile *os.File) {
+		err := descriptionsFile.Close()
+		if err != nil {
+			log.Printf("could not decode json command descriptions %s\n", err.Error())
+		}
+	}(descriptionsFile)
+	jsonParser := json.NewDecoder(descriptionsFile)
+	err = jsonParser.Decode(&descriptions)
+	return descriptions, err
+}
+
+func SaveRecipients(pathFile string, recipients []telegram.Recipient) error {
+	data, err := json.MarshalIndent(recipients, "", "  ")
+	if err != nil {
+		return err
+	}
+	// write aside and rename, so a crash never leaves a truncated recipients file
+	tmpFile := pathFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, pathFile)
+}
@@ -1,9 +1,34 @@
 package entity
 
+import "time"
+
+// CalendarEventDateLayout is the layout of CalendarEvent.Date, always expressed in UTC.
+const CalendarEventDateLayout = "2006-01-02 15:04:05"
+
 type CalendarEvent struct {
 	Date     string `json:"date"`
 	Country  string `json:"country"`
 	Event    string `json:"event"`
 	Currency string `json:"currency"`
 	Impact   string `json:"impact"`
+	// Actual, Forecast, Previous and Change are nil when not known, e.g. Actual before the release
+	Actual   *float64 `json:"actual"`
+	Forecast *float64 `json:"forecast"`
+	Previous *float64 `json:"previous"`
+	Change   *float64 `json:"change"`
+	Unit     string   `json:"unit"`
+}
+
+// Time parses the event Date as a UTC time.
+func (e CalendarEvent) Time() (time.Time, error) {
+	return time.Parse(CalendarEventDateLayout, e.Date)
+}
+
+// Key identifies an event across refreshes: name, currency and the day it was scheduled for.
+func (e CalendarEvent) Key() string {
+	day := e.Date
+	if len(day) > len("2006-01-02") {
+		day = day[:len("2006-01-02")]
+	}
+	return e.Event + "|" + e.Currency + "|" + day
 }
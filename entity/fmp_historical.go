@@ -1,6 +1,7 @@
 package entity
 
 type FmpHistorical struct {
+	Date          string  `json:"date"`
 	Close         float32 `json:"close"`
 	Open          float32 `json:"open"`
 	High          float32 `json:"high"`
@@ -0,0 +1,7 @@
+package entity
+
+// Holiday is a day, formatted as 2006-01-02, on which a market is closed.
+type Holiday struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
@@ -0,0 +1,13 @@
+package telegram
+
+// BotCommand is a command shown in the Telegram menu button.
+type BotCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+// BotCommandScope selects the chats where a list of BotCommand is shown,
+// e.g. "default", "all_private_chats" or "all_group_chats".
+type BotCommandScope struct {
+	Type string `json:"type"`
+}
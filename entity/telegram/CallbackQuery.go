@@ -0,0 +1,17 @@
+package telegram
+
+import "fmt"
+
+// CallbackQuery is sent when a user presses a button of an inline keyboard.
+// Message is the message carrying the keyboard, Data the callback_data of the pressed button.
+type CallbackQuery struct {
+	Id      string  `json:"id"`
+	From    User    `json:"from"`
+	Message Message `json:"message"`
+	Data    string  `json:"data"`
+}
+
+// Implements the fmt.String interface to get the representation of a CallbackQuery as a string.
+func (c CallbackQuery) String() string {
+	return fmt.Sprintf("(id: %s, from: %s, data: %s)", c.Id, c.From, c.Data)
+}
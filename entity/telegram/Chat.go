@@ -3,8 +3,10 @@ package telegram
 import "fmt"
 
 // A Chat indicates the conversation to which the Message belongs.
+// Type is "private", "group", "supergroup" or "channel".
 type Chat struct {
-	Id int `json:"id"`
+	Id   int    `json:"id"`
+	Type string `json:"type"`
 }
 
 // Implements the fmt.String interface to get the representation of a Chat as a string.
@@ -0,0 +1,13 @@
+package telegram
+
+// ChatMember is the membership of a user in a chat returned by getChatMember.
+// Status is "creator", "administrator", "member", "restricted", "left" or "kicked".
+type ChatMember struct {
+	Status string `json:"status"`
+	User   User   `json:"user"`
+}
+
+// IsAdmin tells whether the member can change the chat settings.
+func (m ChatMember) IsAdmin() bool {
+	return m.Status == "creator" || m.Status == "administrator"
+}
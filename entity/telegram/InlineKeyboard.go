@@ -0,0 +1,12 @@
+package telegram
+
+// InlineKeyboardMarkup is a keyboard shown under a message, its buttons producing a CallbackQuery.
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// InlineKeyboardButton is a button of an InlineKeyboardMarkup.
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data,omitempty"`
+}
@@ -0,0 +1,31 @@
+package telegram
+
+import "fmt"
+
+// InlineQuery is sent when a user types @BotName followed by a query in any chat.
+type InlineQuery struct {
+	Id     string `json:"id"`
+	From   User   `json:"from"`
+	Query  string `json:"query"`
+	Offset string `json:"offset"`
+}
+
+// Implements the fmt.String interface to get the representation of an InlineQuery as a string.
+func (q InlineQuery) String() string {
+	return fmt.Sprintf("(id: %s, from: %s, query: %s)", q.Id, q.From, q.Query)
+}
+
+// InlineQueryResultArticle is an inline result that sends InputMessageContent to the chat when chosen.
+type InlineQueryResultArticle struct {
+	Type                string                  `json:"type"`
+	Id                  string                  `json:"id"`
+	Title               string                  `json:"title"`
+	Description         string                  `json:"description,omitempty"`
+	InputMessageContent InputTextMessageContent `json:"input_message_content"`
+}
+
+// InputTextMessageContent is the text of the message sent by an inline result.
+type InputTextMessageContent struct {
+	MessageText string `json:"message_text"`
+	ParseMode   string `json:"parse_mode,omitempty"`
+}
@@ -5,11 +5,14 @@ import "fmt"
 // Message is a Telegram object that can be found in an update.
 // Note that not all Update contains a Message. Update for an Inline Query doesn't.
 type Message struct {
-	Text     string   `json:"text"`
-	Chat     Chat     `json:"chat"`
-	Audio    Audio    `json:"audio"`
-	Voice    Voice    `json:"voice"`
-	Document Document `json:"document"`
+	MessageId       int      `json:"message_id"`
+	From            User     `json:"from"`
+	Text            string   `json:"text"`
+	Chat            Chat     `json:"chat"`
+	MessageThreadId int      `json:"message_thread_id"`
+	Audio           Audio    `json:"audio"`
+	Voice           Voice    `json:"voice"`
+	Document        Document `json:"document"`
 }
 
 // Implements the fmt.String interface to get the representation of a Message as a string.
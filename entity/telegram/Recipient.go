@@ -3,4 +3,13 @@ package telegram
 type Recipient struct {
 	ChatId          int `json:"chatId"`
 	MessageThreadId int `json:"messageThreadId"`
+	// Timezone is the IANA name of the zone used to render dates, UTC when empty
+	Timezone string `json:"timezone,omitempty"`
+	// Currencies and MinImpact filter the events sent to the recipient, EUR, GBP, USD, JPY and High when empty
+	Currencies []string `json:"currencies,omitempty"`
+	MinImpact  string   `json:"minImpact,omitempty"`
+	// DeliveryTime is the HH:MM time, in Timezone, of the daily calendar, 00:01 UTC when empty
+	DeliveryTime string `json:"deliveryTime,omitempty"`
+	// LastDelivery is the day, YYYY-MM-DD in the zone of DeliveryTime, of the last daily calendar sent
+	LastDelivery string `json:"lastDelivery,omitempty"`
 }
@@ -0,0 +1,12 @@
+package telegram
+
+import "encoding/json"
+
+// Response is the envelope of every Bot API answer. Result holds the method result when Ok,
+// Description the reason of the failure otherwise.
+type Response struct {
+	Ok          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+}
@@ -3,9 +3,13 @@ package telegram
 import "fmt"
 
 // Update is a Telegram object that we receive every time an user interacts with the bot.
+// CallbackQuery is nil unless the user pressed a button of an inline keyboard,
+// InlineQuery is nil unless the user typed @BotName in a chat.
 type Update struct {
-	UpdateId int     `json:"update_id"`
-	Message  Message `json:"message"`
+	UpdateId      int            `json:"update_id"`
+	Message       Message        `json:"message"`
+	CallbackQuery *CallbackQuery `json:"callback_query"`
+	InlineQuery   *InlineQuery   `json:"inline_query"`
 }
 
 // Implements the fmt.String interface to get the representation of an Update as a string.
@@ -0,0 +1,17 @@
+package telegram
+
+import "fmt"
+
+// User is the Telegram user or bot who sent a Message, a CallbackQuery or an InlineQuery.
+type User struct {
+	Id           int    `json:"id"`
+	IsBot        bool   `json:"is_bot"`
+	FirstName    string `json:"first_name"`
+	Username     string `json:"username"`
+	LanguageCode string `json:"language_code"`
+}
+
+// Implements the fmt.String interface to get the representation of a User as a string.
+func (u User) String() string {
+	return fmt.Sprintf("(id: %d, username: %s)", u.Id, u.Username)
+}
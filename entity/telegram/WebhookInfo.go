@@ -0,0 +1,12 @@
+package telegram
+
+// WebhookInfo is the current webhook status returned by getWebhookInfo.
+// LastErrorDate is a unix time, zero when no delivery failed.
+type WebhookInfo struct {
+	Url                string   `json:"url"`
+	PendingUpdateCount int      `json:"pending_update_count"`
+	LastErrorDate      int64    `json:"last_error_date"`
+	LastErrorMessage   string   `json:"last_error_message"`
+	MaxConnections     int      `json:"max_connections"`
+	AllowedUpdates     []string `json:"allowed_updates"`
+}
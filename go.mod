@@ -7,6 +7,7 @@ require (
 	github.com/go-co-op/gocron v1.37.0
 	github.com/gorilla/mux v1.8.1
 	github.com/rs/cors v1.11.0
+	go.etcd.io/bbolt v1.3.11
 	golang.org/x/oauth2 v0.21.0
 	google.golang.org/api v0.187.0
 )
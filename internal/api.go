@@ -1,24 +1,41 @@
 package internal
 
 import (
+	"bot/conf"
 	"bot/entity/telegram"
+	"crypto/subtle"
 	"encoding/json"
 	"github.com/gorilla/mux"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
-func RegisterHandlers(router *mux.Router, service Service) {
-	router.HandleFunc("/handle", HandleTelegramWebHook(service)).Methods(http.MethodPost)
+// the header carrying the secret_token given to setWebhook
+const telegramSecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+func RegisterHandlers(router *mux.Router, service Service, commands *CommandRegistry, config conf.Config) {
+	if config.WebhookSecretToken == "" {
+		log.Printf("no webhook secret token configured, incoming updates are not authenticated")
+	}
+	updates := newUpdateDeduplicator(time.Duration(config.UpdateDedupMinutes) * time.Minute)
+	router.HandleFunc("/handle", HandleTelegramWebHook(service, commands, config.WebhookSecretToken, updates)).Methods(http.MethodPost)
 }
 
-func HandleTelegramWebHook(service Service) func(w http.ResponseWriter, r *http.Request) {
+// HandleTelegramWebHook dispatches the updates sent by Telegram. When secretToken is set, requests without
+// the same X-Telegram-Bot-Api-Secret-Token header are rejected; updates already received are ignored.
+func HandleTelegramWebHook(service Service, commands *CommandRegistry, secretToken string, updates *updateDeduplicator) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if secretToken != "" &&
+			subtle.ConstantTimeCompare([]byte(r.Header.Get(telegramSecretTokenHeader)), []byte(secretToken)) != 1 {
+			log.Printf("rejected update from %s: invalid secret token", r.RemoteAddr)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
 		var update telegram.Update
-		var message string
-		var siteInfo string
 
 		err := json.NewDecoder(r.Body).Decode(&update)
 		if err != nil {
@@ -26,36 +43,50 @@ func HandleTelegramWebHook(service Service) func(w http.ResponseWriter, r *http.
 			return
 		}
 
-		command := getCommand(update.Message.Text)
-		switch command {
-		case 1:
-			message = service.PrepareStartMessageToTelegramChat()
-			log.Printf("send to chatId, %s", strconv.Itoa(update.Message.Chat.Id))
-			telegramResponseBody, err := service.SendTextToTelegramChat(update.Message.Chat.Id, 0, message)
-			if err != nil {
-				log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
-			} else {
-				log.Printf("turbine infos %s successfully distributed to chat id %d", siteInfo, update.Message.Chat.Id)
-			}
-			return
-		default:
-			message = service.PrepareCommandNotFoundMessageToTelegramChat()
-			log.Printf("send to chatId, %s", strconv.Itoa(update.Message.Chat.Id))
-			telegramResponseBody, err := service.SendTextToTelegramChat(update.Message.Chat.Id, 0, message)
-			if err != nil {
-				log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
-			} else {
-				log.Printf("turbine infos %s successfully distributed to chat id %d", siteInfo, update.Message.Chat.Id)
-			}
+		if !updates.firstSeen(update.UpdateId, time.Now()) {
+			log.Printf("ignored update %d: already received", update.UpdateId)
 			return
 		}
+
+		dispatchUpdate(service, commands, update)
 	}
 }
 
-func getCommand(command string) int {
-	if strings.Contains(command, "/start") {
-		return 1
+// dispatchUpdate answers the button presses, the inline queries and the commands of an update.
+func dispatchUpdate(service Service, commands *CommandRegistry, update telegram.Update) {
+	if update.InlineQuery != nil {
+		telegramResponseBody, err := service.AnswerInlineQuery(update.InlineQuery.Id, service.PrepareInlineQueryResults(*update.InlineQuery))
+		if err != nil {
+			log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
+		}
+		return
+	}
+
+	if update.CallbackQuery != nil {
+		var text string
+		if strings.HasPrefix(update.CallbackQuery.Data, settingsCallbackPrefix) {
+			text = service.HandleSettingsCallback(*update.CallbackQuery)
+		}
+		telegramResponseBody, err := service.AnswerCallbackQuery(update.CallbackQuery.Id, text)
+		if err != nil {
+			log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
+		}
+		return
 	}
 
-	return 0
+	message, parseMode, ok := commands.Dispatch(service, update.Message)
+	if !ok || message == "" {
+		return
+	}
+	reply(service, update.Message, message, parseMode)
+}
+
+func reply(service Service, message telegram.Message, text string, parseMode string) {
+	log.Printf("send to chatId, %s", strconv.Itoa(message.Chat.Id))
+	_, telegramResponseBody, err := service.SendTextToTelegramChat(message.Chat.Id, message.MessageThreadId, text, parseMode)
+	if err != nil {
+		log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
+	} else {
+		log.Printf("reply successfully distributed to chat id %d", message.Chat.Id)
+	}
 }
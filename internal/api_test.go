@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"bot/conf"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecretToken = "s3cr3t-t0k3n"
+
+func newTestWebhook(service Service) *httptest.Server {
+	router := mux.NewRouter()
+	RegisterHandlers(router, service, NewCommandRegistry("EconomicBot"), conf.Config{WebhookSecretToken: testSecretToken})
+	return httptest.NewServer(router)
+}
+
+func postUpdate(t *testing.T, server *httptest.Server, secretToken string, update string) int {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodPost, server.URL+"/handle", strings.NewReader(update))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if secretToken != "" {
+		request.Header.Set(telegramSecretTokenHeader, secretToken)
+	}
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+	return response.StatusCode
+}
+
+func TestWebhookSecretToken(t *testing.T) {
+	service := newFakeService()
+	server := newTestWebhook(service)
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		secretToken string
+		status      int
+		sent        int
+	}{
+		{"missing", "", http.StatusUnauthorized, 0},
+		{"wrong", "s3cr3t-t0k3N", http.StatusUnauthorized, 0},
+		{"prefix", "s3cr3t", http.StatusUnauthorized, 0},
+		{"valid", testSecretToken, http.StatusOK, 1},
+	}
+	for i, tt := range tests {
+		update := `{"update_id": ` + strconv.Itoa(i+1) + `, "message": {"text": "/unknown", "chat": {"id": 42}}}`
+		if status := postUpdate(t, server, tt.secretToken, update); status != tt.status {
+			t.Errorf("%s secret token: status = %d, want %d", tt.name, status, tt.status)
+		}
+		if len(*service.sent) != tt.sent {
+			t.Errorf("%s secret token: %d messages sent, want %d", tt.name, len(*service.sent), tt.sent)
+		}
+	}
+}
+
+func TestWebhookReplayedUpdate(t *testing.T) {
+	service := newFakeService()
+	server := newTestWebhook(service)
+	defer server.Close()
+
+	update := `{"update_id": 1000, "message": {"text": "/unknown", "chat": {"id": 42}}}`
+	for i := 0; i < 3; i++ {
+		if status := postUpdate(t, server, testSecretToken, update); status != http.StatusOK {
+			t.Errorf("post %d: status = %d, want %d", i, status, http.StatusOK)
+		}
+	}
+	if len(*service.sent) != 1 {
+		t.Errorf("%d messages sent for a replayed update, want 1", len(*service.sent))
+	}
+
+	postUpdate(t, server, testSecretToken, `{"update_id": 1001, "message": {"text": "/unknown", "chat": {"id": 42}}}`)
+	if len(*service.sent) != 2 {
+		t.Errorf("%d messages sent after a new update, want 2", len(*service.sent))
+	}
+}
+
+func TestUpdateDeduplicatorWindow(t *testing.T) {
+	updates := newUpdateDeduplicator(10 * time.Minute)
+	now := time.Date(2025, 3, 5, 14, 0, 0, 0, time.UTC)
+
+	if !updates.firstSeen(1, now) {
+		t.Errorf("update 1 not first seen")
+	}
+	if updates.firstSeen(1, now.Add(9*time.Minute)) {
+		t.Errorf("update 1 replayed within the window is first seen")
+	}
+	if !updates.firstSeen(1, now.Add(11*time.Minute)) {
+		t.Errorf("update 1 replayed after the window is not first seen")
+	}
+}
+
+func TestWebhookIgnoresPlainMessages(t *testing.T) {
+	service := newFakeService()
+	server := newTestWebhook(service)
+	defer server.Close()
+
+	updates := []string{
+		// a member joining the group
+		`{"update_id": 1, "message": {"chat": {"id": -100, "type": "supergroup"}, "new_chat_members": [{"id": 7}]}}`,
+		// an event shared through the inline results
+		`{"update_id": 2, "message": {"text": "📅 CPI m/m", "chat": {"id": -100, "type": "supergroup"}, "via_bot": {"id": 9}}}`,
+		`{"update_id": 3, "message": {"text": "hello", "chat": {"id": 42, "type": "private"}}}`,
+	}
+	for _, update := range updates {
+		postUpdate(t, server, testSecretToken, update)
+	}
+	if len(*service.sent) != 0 {
+		t.Errorf("%d messages sent to plain messages, want none: %q", len(*service.sent), *service.sent)
+	}
+}
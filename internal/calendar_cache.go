@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"bot/entity"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// days kept in the cache before today
+const calendarCacheRetentionDays = 31
+
+// calendarCache keeps the events fetched by the calendar refresh in memory and in an on-disk snapshot,
+// so commands can answer without calling the provider.
+type calendarCache struct {
+	mu   sync.RWMutex
+	path string
+	// UTC days, formatted as 2006-01-02, whose events have been fetched
+	days map[string]bool
+	// events by UTC day, formatted as 2006-01-02; events with the same name can happen the same day
+	events map[string][]entity.CalendarEvent
+}
+
+type calendarCacheSnapshot struct {
+	Days   []string               `json:"days"`
+	Events []entity.CalendarEvent `json:"events"`
+}
+
+// newCalendarCache loads the on-disk snapshot at path, if any. An empty path keeps the cache in memory only.
+func newCalendarCache(path string) *calendarCache {
+	c := &calendarCache{path: path, days: map[string]bool{}, events: map[string][]entity.CalendarEvent{}}
+	if path == "" {
+		return c
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c
+	}
+	if err != nil {
+		log.Printf("could not read calendar cache %s", err.Error())
+		return c
+	}
+
+	var snapshot calendarCacheSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		log.Printf("could not decode calendar cache %s", err.Error())
+		return c
+	}
+	for _, day := range snapshot.Days {
+		c.days[day] = true
+	}
+	for _, e := range snapshot.Events {
+		c.events[eventDay(e)] = append(c.events[eventDay(e)], e)
+	}
+	return c
+}
+
+// store replaces the cached events of the days covered by the fetched ones, from the day of from
+// up to the day of to, or of the last event when the provider published a shorter range.
+func (c *calendarCache) store(events []entity.CalendarEvent, from time.Time, to time.Time, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	first, last := coveredDays(events, from, to)
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		c.days[day.Format("2006-01-02")] = true
+		delete(c.events, day.Format("2006-01-02"))
+	}
+	for _, e := range events {
+		day := eventDay(e)
+		if day < first.Format("2006-01-02") || day > last.Format("2006-01-02") {
+			continue
+		}
+		c.events[day] = append(c.events[day], e)
+	}
+
+	oldest := utcDay(now).AddDate(0, 0, -calendarCacheRetentionDays).Format("2006-01-02")
+	for day := range c.days {
+		if day < oldest {
+			delete(c.days, day)
+		}
+	}
+	for day := range c.events {
+		if day < oldest {
+			delete(c.events, day)
+		}
+	}
+
+	if err := c.save(); err != nil {
+		log.Printf("could not save calendar cache %s", err.Error())
+	}
+}
+
+func (c *calendarCache) save() error {
+	if c.path == "" {
+		return nil
+	}
+	snapshot := calendarCacheSnapshot{Events: c.all()}
+	for day := range c.days {
+		snapshot.Days = append(snapshot.Days, day)
+	}
+	sort.Strings(snapshot.Days)
+	sortEvents(snapshot.Events)
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	tmpFile := c.path + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, c.path)
+}
+
+// eventsBetween returns the cached events in [start, end) sorted by date, and whether the whole range is cached.
+func (c *calendarCache) eventsBetween(start time.Time, end time.Time) ([]entity.CalendarEvent, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for day := utcDay(start); day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !c.days[day.Format("2006-01-02")] {
+			return nil, false
+		}
+	}
+
+	var events []entity.CalendarEvent
+	for _, e := range c.all() {
+		date, err := e.Time()
+		if err != nil {
+			continue
+		}
+		if !date.Before(start) && date.Before(end) {
+			events = append(events, e)
+		}
+	}
+	sortEvents(events)
+	return events, true
+}
+
+// all returns the cached events of every day, in no particular order.
+func (c *calendarCache) all() []entity.CalendarEvent {
+	var events []entity.CalendarEvent
+	for _, dayEvents := range c.events {
+		events = append(events, dayEvents...)
+	}
+	return events
+}
+
+// eventDay returns the UTC day of an event, formatted as 2006-01-02.
+func eventDay(e entity.CalendarEvent) string {
+	if len(e.Date) < len("2006-01-02") {
+		return e.Date
+	}
+	return e.Date[:len("2006-01-02")]
+}
+
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// sortEvents orders events by date, then by name; the layout of Date sorts lexicographically.
+func sortEvents(events []entity.CalendarEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Date != events[j].Date {
+			return events[i].Date < events[j].Date
+		}
+		return events[i].Event < events[j].Event
+	})
+}
+
+// upcoming returns the cached events after now sorted by date.
+func (c *calendarCache) upcoming(now time.Time) []entity.CalendarEvent {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var events []entity.CalendarEvent
+	for _, e := range c.all() {
+		date, err := e.Time()
+		if err == nil && date.After(now) {
+			events = append(events, e)
+		}
+	}
+	sortEvents(events)
+	return events
+}
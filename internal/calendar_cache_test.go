@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"bot/entity"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCalendarCacheShorterFeed(t *testing.T) {
+	cache := newCalendarCache("")
+	// a weekly feed fetched on Thursday for the coming week ends on Friday
+	now := time.Date(2025, 3, 13, 6, 0, 0, 0, time.UTC)
+	cache.store([]entity.CalendarEvent{
+		{Date: "2025-03-13 12:30:00", Event: "PPI m/m", Currency: "USD"},
+		{Date: "2025-03-14 07:00:00", Event: "GDP m/m", Currency: "GBP"},
+	}, now, now.AddDate(0, 0, calendarRefreshDays), now)
+
+	tests := []struct {
+		day    time.Time
+		events int
+		cached bool
+	}{
+		{time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC), 1, true},
+		{time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), 1, true},
+		// never fetched: no calendar rather than an empty one
+		{time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC), 0, false},
+		{time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC), 0, false},
+	}
+	for _, tt := range tests {
+		events, cached := cache.eventsBetween(tt.day, tt.day.AddDate(0, 0, 1))
+		if len(events) != tt.events || cached != tt.cached {
+			t.Errorf("eventsBetween(%s) = %d events, %t, want %d, %t", tt.day.Format("2006-01-02"), len(events), cached, tt.events, tt.cached)
+		}
+	}
+}
+
+func TestCalendarCacheSameNameEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar_cache.json")
+	now := time.Date(2025, 3, 13, 6, 0, 0, 0, time.UTC)
+	day := time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC)
+	events := []entity.CalendarEvent{
+		{Date: "2025-03-13 08:30:00", Event: "ECB President Lagarde Speaks", Currency: "EUR"},
+		{Date: "2025-03-13 16:00:00", Event: "ECB President Lagarde Speaks", Currency: "EUR"},
+	}
+
+	cache := newCalendarCache(path)
+	cache.store(events, now, now, now)
+	if got, _ := cache.eventsBetween(day, day.AddDate(0, 0, 1)); len(got) != 2 {
+		t.Errorf("got %d events, want both speeches", len(got))
+	}
+
+	// the on-disk snapshot keeps both
+	if got, _ := newCalendarCache(path).eventsBetween(day, day.AddDate(0, 0, 1)); len(got) != 2 {
+		t.Errorf("got %d events after reloading, want both speeches", len(got))
+	}
+
+	// a refresh replaces the events of the day instead of adding to them
+	cache.store(events[:1], now, now, now)
+	if got, _ := cache.eventsBetween(day, day.AddDate(0, 0, 1)); len(got) != 1 {
+		t.Errorf("got %d events after a refresh, want 1", len(got))
+	}
+}
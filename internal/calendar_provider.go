@@ -0,0 +1,264 @@
+package internal
+
+import (
+	"bot/conf"
+	"bot/entity"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CalendarProvider is a source of economic calendar events.
+// GetEvents returns the events of the days from the day of from to the day of to, included,
+// with Date formatted as entity.CalendarEventDateLayout in UTC.
+type CalendarProvider interface {
+	GetEvents(from time.Time, to time.Time) ([]entity.CalendarEvent, error)
+}
+
+// NewCalendarProvider returns the provider selected by name in the config.
+func NewCalendarProvider(config conf.Config) (CalendarProvider, error) {
+	switch config.CalendarProvider {
+	case "", "fmp":
+		return fmpCalendarProvider{
+			url:    config.EconomicCalendarUrl,
+			apiKey: config.EconomicCalendarApyKey,
+		}, nil
+	case "forexfactory":
+		return forexFactoryCalendarProvider{
+			source:   config.CalendarSource,
+			location: loadLocation(config.CalendarTimezone),
+		}, nil
+	case "ics":
+		return icsCalendarProvider{
+			source:   config.CalendarSource,
+			location: loadLocation(config.CalendarTimezone),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown calendar provider %q", config.CalendarProvider)
+	}
+}
+
+// fmpCalendarProvider reads events from the Financial Modeling Prep economic calendar API.
+type fmpCalendarProvider struct {
+	url    string
+	apiKey string
+}
+
+type fmpCalendarEvent struct {
+	Date     string   `json:"date"`
+	Country  string   `json:"country"`
+	Event    string   `json:"event"`
+	Currency string   `json:"currency"`
+	Impact   string   `json:"impact"`
+	Actual   *float64 `json:"actual"`
+	Estimate *float64 `json:"estimate"`
+	Previous *float64 `json:"previous"`
+	Change   *float64 `json:"change"`
+	Unit     string   `json:"unit"`
+}
+
+func (p fmpCalendarProvider) GetEvents(from time.Time, to time.Time) ([]entity.CalendarEvent, error) {
+
+	u, err := url.Parse(p.url)
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	q.Set("from", from.Format("2006-01-02"))
+	q.Set("to", to.Format("2006-01-02"))
+	q.Set("apikey", p.apiKey)
+
+	u.RawQuery = q.Encode()
+	log.Println("Calling " + u.String())
+
+	response, err := http.Get(u.String())
+	if err != nil {
+		log.Printf("error while calling Economic Calendar %s", err.Error())
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Printf("error when closing Economic Calendar response: %s", err.Error())
+		}
+	}(response.Body)
+	log.Println(response.Status)
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("economic calendar answered %s", response.Status)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var fmpEvents []fmpCalendarEvent
+	if err := json.Unmarshal(body, &fmpEvents); err != nil {
+		log.Printf("error while parsing Economic Calendar response %s", err.Error())
+		return nil, err
+	}
+
+	events := make([]entity.CalendarEvent, 0, len(fmpEvents))
+	for _, e := range fmpEvents {
+		events = append(events, entity.CalendarEvent{
+			Date:     e.Date,
+			Country:  e.Country,
+			Event:    e.Event,
+			Currency: e.Currency,
+			Impact:   e.Impact,
+			Actual:   e.Actual,
+			Forecast: e.Estimate,
+			Previous: e.Previous,
+			Change:   e.Change,
+			Unit:     e.Unit,
+		})
+	}
+
+	return events, nil
+}
+
+// openCalendarSource opens an HTTP(S) URL or a local file path, so feed based providers
+// can also be run offline against recorded files.
+func openCalendarSource(source string) (io.ReadCloser, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		log.Println("Calling " + source)
+		response, err := http.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		log.Println(response.Status)
+		if response.StatusCode != http.StatusOK {
+			_ = response.Body.Close()
+			return nil, fmt.Errorf("calendar source answered %s", response.Status)
+		}
+		return response.Body, nil
+	}
+	return os.Open(strings.TrimPrefix(source, "file://"))
+}
+
+// normalizeImpact maps the impact labels used by the different feeds to Low, Medium and High.
+func normalizeImpact(impact string) string {
+	switch strings.ToLower(strings.TrimSpace(impact)) {
+	case "high", "red", "3":
+		return "High"
+	case "medium", "moderate", "orange", "ora", "2":
+		return "Medium"
+	case "low", "yellow", "yel", "1", "non-economic":
+		return "Low"
+	case "holiday":
+		return "Holiday"
+	default:
+		return strings.TrimSpace(impact)
+	}
+}
+
+// countryForCurrency returns the country code used by FMP for the given currency.
+func countryForCurrency(currency string) string {
+	switch strings.ToUpper(currency) {
+	case "USD":
+		return "US"
+	case "GBP":
+		return "UK"
+	case "EUR":
+		return "EU"
+	case "JPY":
+		return "JP"
+	case "CAD":
+		return "CA"
+	case "AUD":
+		return "AU"
+	case "NZD":
+		return "NZ"
+	case "CHF":
+		return "CH"
+	case "CNY":
+		return "CN"
+	default:
+		return ""
+	}
+}
+
+// parseEventValue parses released, forecast and previous values such as "0.3%", "215K" or "-1.2B".
+// The numeric part is returned as nil when the value is missing.
+func parseEventValue(value string) (*float64, string) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimLeft(value, "<>~")
+	if value == "" {
+		return nil, ""
+	}
+
+	unit := ""
+	for _, suffix := range []string{"%", "K", "M", "B", "T"} {
+		if strings.HasSuffix(value, suffix) {
+			unit = suffix
+			value = strings.TrimSuffix(value, suffix)
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+	if err != nil {
+		return nil, ""
+	}
+	return &number, unit
+}
+
+// setEventValue parses a textual value into field, recording its unit on the event when not known yet.
+func setEventValue(event *entity.CalendarEvent, field **float64, value string) {
+	number, unit := parseEventValue(value)
+	*field = number
+	if number != nil && event.Unit == "" {
+		event.Unit = unit
+	}
+}
+
+// completeEventChange fills Change with actual minus previous when the provider does not report it.
+func completeEventChange(event *entity.CalendarEvent) {
+	if event.Change == nil && event.Actual != nil && event.Previous != nil {
+		change := *event.Actual - *event.Previous
+		event.Change = &change
+	}
+}
+
+// loadLocation returns the named location, falling back to UTC when empty or unknown.
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("unknown timezone %s, using UTC: %v", name, err)
+		return time.UTC
+	}
+	return loc
+}
+
+// eventsBetween keeps only the events falling on the UTC days from the day of from to the day of to, included.
+func eventsBetween(events []entity.CalendarEvent, from time.Time, to time.Time) []entity.CalendarEvent {
+	from = from.UTC()
+	to = to.UTC()
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+
+	var filtered []entity.CalendarEvent
+	for _, e := range events {
+		t, err := e.Time()
+		if err != nil {
+			log.Printf("Error parsing date %s: %v", e.Date, err)
+			continue
+		}
+		if !t.Before(start) && t.Before(end) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"bot/entity"
+	"github.com/go-co-op/gocron"
+	"log"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultCalendarRefreshMinutes = 30
+	// days ahead of today fetched on every refresh
+	calendarRefreshDays = 7
+)
+
+// ScheduledCalendarRefresh periodically fetches the events of the coming week into the cache, notifies the changes
+// since the previous refresh and keeps the pre-event reminders in sync.
+func (s service) ScheduledCalendarRefresh() {
+	refreshMinutes := s.config.CalendarRefreshMinutes
+	if refreshMinutes <= 0 {
+		refreshMinutes = defaultCalendarRefreshMinutes
+	}
+	offsets := s.config.ReminderMinutes
+	if len(offsets) == 0 {
+		offsets = defaultReminderMinutes
+	}
+
+	s1 := gocron.NewScheduler(time.UTC)
+	_, err := s1.Every(refreshMinutes).Minutes().Do(func() {
+		now := time.Now().UTC()
+
+		from, to := now, now.AddDate(0, 0, calendarRefreshDays)
+		events, err := s.provider.GetEvents(from, to)
+		if err != nil {
+			log.Printf("got error when calling Economic Calendar API %s", err.Error())
+			return
+		}
+
+		s.cache.store(events, from, to, now)
+		if err := s.history.Save(events); err != nil {
+			log.Printf("could not save events history %s", err.Error())
+		}
+
+		changes := s.snapshot.update(events, from, to, now)
+		if !changes.empty() {
+			s.notifyCalendarChanges(changes)
+		}
+
+		relevant := acceptedByAny(s.recipients.All(), events)
+
+		s.reminders.reschedule(relevant, offsets, now, func(e entity.CalendarEvent, minutes int) {
+			for _, recipient := range s.recipients.All() {
+				if !acceptsEvent(recipient, e) {
+					continue
+				}
+				message := s.PrepareReminderMessage(e, minutes, loadLocation(recipient.Timezone))
+				log.Printf("send to chatId, %s", strconv.Itoa(recipient.ChatId))
+				_, telegramResponseBody, err := s.SendTextToTelegramChat(recipient.ChatId, recipient.MessageThreadId, message, "")
+				if err != nil {
+					log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
+				} else {
+					log.Printf("reminder successfully distributed to chat id %d", recipient.ChatId)
+				}
+			}
+		})
+	})
+	s1.StartAsync()
+	if err != nil {
+		log.Printf("error creating job: %v", err)
+	}
+	_, t := s1.NextRun()
+	log.Printf("next run at: %s", t)
+}
+
+func (s service) notifyCalendarChanges(changes calendarChanges) {
+	for _, recipient := range s.recipients.All() {
+		recipientChanges := changes.forRecipient(recipient)
+		if recipientChanges.empty() {
+			continue
+		}
+		message := s.PrepareCalendarChangesMessage(recipientChanges, loadLocation(recipient.Timezone))
+		log.Printf("send to chatId, %s", strconv.Itoa(recipient.ChatId))
+		_, telegramResponseBody, err := s.SendTextToTelegramChat(recipient.ChatId, recipient.MessageThreadId, message, "")
+		if err != nil {
+			log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
+		} else {
+			log.Printf("calendar changes successfully distributed to chat id %d", recipient.ChatId)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"bot/entity"
+	"github.com/enescakir/emoji"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// centralBank describes how the rate decisions of a bank are named by the calendar providers.
+type centralBank struct {
+	Name     string
+	Currency string
+	// names of the headline rate of a meeting, one per provider; the other rates set at the meeting,
+	// such as the ECB deposit facility rate, are ordinary events
+	keywords []string
+}
+
+var centralBanks = []centralBank{
+	{"FOMC", "USD", []string{"fed interest rate decision", "federal funds rate", "fomc rate decision", "fed funds rate"}},
+	{"ECB", "EUR", []string{"ecb interest rate decision", "main refinancing rate", "ecb rate decision"}},
+	{"BoE", "GBP", []string{"boe interest rate decision", "official bank rate", "boe rate decision"}},
+	{"BoJ", "JPY", []string{"boj interest rate decision", "boj policy rate", "boj rate decision"}},
+}
+
+// rateDecisionBank tells whether an event is the rate decision of one of the tracked central banks.
+func rateDecisionBank(e entity.CalendarEvent) (centralBank, bool) {
+	name := strings.ToLower(e.Event)
+	for _, bank := range centralBanks {
+		if !strings.EqualFold(bank.Currency, e.Currency) {
+			continue
+		}
+		for _, keyword := range bank.keywords {
+			if strings.Contains(name, keyword) {
+				return bank, true
+			}
+		}
+	}
+	return centralBank{}, false
+}
+
+// PrepareRateDecisionMessage renders a rate decision with previous and expected rate and, once released, the outcome.
+func (s service) PrepareRateDecisionMessage(bank centralBank, e entity.CalendarEvent, loc *time.Location) string {
+	return emoji.ClassicalBuilding.String() + "  " + bank.Name + " RATE DECISION  " + GetEmojiCountry(e.Country) + "\n" +
+		emoji.Calendar.String() + "  DATE: " + formatEventDate(e, loc) + "\n" +
+		emoji.HourglassDone.String() + "  PREVIOUS RATE: " + FormatEventValue(e.Previous, e.Unit) + "\n" +
+		emoji.CrystalBall.String() + "  EXPECTED RATE: " + FormatEventValue(e.Forecast, e.Unit) + "\n" +
+		emoji.BarChart.String() + "  OUTCOME: " + rateDecisionOutcome(e) + "\n\n"
+}
+
+// rateDecisionOutcome compares the decided rate with the previous one and with the expectations.
+func rateDecisionOutcome(e entity.CalendarEvent) string {
+	if e.Actual == nil {
+		return "pending"
+	}
+
+	outcome := "HOLD at " + FormatEventValue(e.Actual, e.Unit)
+	if e.Previous != nil {
+		move := *e.Actual - *e.Previous
+		switch {
+		case move > 1e-9:
+			outcome = "HIKE +" + formatRateMove(move) + " to " + FormatEventValue(e.Actual, e.Unit)
+		case move < -1e-9:
+			outcome = "CUT " + formatRateMove(move) + " to " + FormatEventValue(e.Actual, e.Unit)
+		}
+	}
+
+	if e.Forecast != nil {
+		switch {
+		case math.Abs(*e.Actual-*e.Forecast) < 1e-9:
+			outcome = outcome + ", as expected"
+		case *e.Actual > *e.Forecast:
+			outcome = outcome + ", more hawkish than expected"
+		default:
+			outcome = outcome + ", more dovish than expected"
+		}
+	}
+	return outcome
+}
+
+// formatRateMove renders a rate change in basis points, e.g. -25bp.
+func formatRateMove(move float64) string {
+	return strconv.FormatFloat(math.Round(move*10000)/100, 'f', -1, 64) + "bp"
+}
+
+// PrepareCentralBanksMessage lists the next meeting of every tracked central bank found in the cached calendar.
+func (s service) PrepareCentralBanksMessage(loc *time.Location) string {
+	upcoming := s.cache.upcoming(time.Now())
+
+	message := emoji.ClassicalBuilding.String() + " Next central bank meetings\n\n"
+	for _, bank := range centralBanks {
+		message = message + bank.Name + " " + GetEmojiCountry(countryForCurrency(bank.Currency)) + ": "
+		found := false
+		for _, e := range upcoming {
+			if b, ok := rateDecisionBank(e); ok && b.Name == bank.Name {
+				message = message + formatEventDate(e, loc) + ", expected " + FormatEventValue(e.Forecast, e.Unit) +
+					" (previous " + FormatEventValue(e.Previous, e.Unit) + ")\n"
+				found = true
+				break
+			}
+		}
+		if !found {
+			message = message + "not in the cached calendar\n"
+		}
+	}
+	return message
+}
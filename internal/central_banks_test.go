@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"bot/conf"
+	"bot/entity"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateDecisionBank(t *testing.T) {
+	tests := []struct {
+		event    string
+		currency string
+		bank     string
+	}{
+		{"Federal Funds Rate", "USD", "FOMC"},
+		{"Fed Interest Rate Decision", "USD", "FOMC"},
+		{"Main Refinancing Rate", "EUR", "ECB"},
+		{"ECB Interest Rate Decision", "EUR", "ECB"},
+		{"Official Bank Rate", "GBP", "BoE"},
+		{"BOJ Policy Rate", "JPY", "BoJ"},
+		// other rates set at the meeting and names of other banks are ordinary events
+		{"Deposit Facility Rate", "EUR", ""},
+		{"Interest Rate Decision", "EUR", ""},
+		{"SNB Policy Rate", "CHF", ""},
+		{"RBA Rate Decision", "AUD", ""},
+		{"Main Refinancing Rate", "USD", ""},
+	}
+	for _, tt := range tests {
+		bank, ok := rateDecisionBank(entity.CalendarEvent{Event: tt.event, Currency: tt.currency})
+		if bank.Name != tt.bank || ok != (tt.bank != "") {
+			t.Errorf("rateDecisionBank(%s, %s) = %s, %t, want %s", tt.event, tt.currency, bank.Name, ok, tt.bank)
+		}
+	}
+}
+
+func TestCalendarOneRateDecisionPerMeeting(t *testing.T) {
+	s := service{config: conf.Config{ParseMode: ParseModeHTML}}
+	events := []entity.CalendarEvent{
+		{Date: "2025-03-06 13:15:00", Country: "EU", Event: "Main Refinancing Rate", Currency: "EUR", Impact: "High",
+			Forecast: float(2.65), Previous: float(2.9), Unit: "%"},
+		{Date: "2025-03-06 13:15:00", Country: "EU", Event: "Deposit Facility Rate", Currency: "EUR", Impact: "High",
+			Forecast: float(2.5), Previous: float(2.75), Unit: "%"},
+	}
+
+	message := s.PrepareEconomicCalendarForNextDayMessage(time.Date(2025, 3, 6, 0, 0, 0, 0, time.UTC), events)
+	if blocks := strings.Count(message, "ECB RATE DECISION"); blocks != 1 {
+		t.Errorf("got %d ECB rate decision blocks, want 1:\n%s", blocks, message)
+	}
+	if !strings.Contains(message, "Deposit Facility Rate") {
+		t.Errorf("deposit facility rate missing:\n%s", message)
+	}
+}
@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"bot/entity"
+	"bot/entity/telegram"
+	"github.com/enescakir/emoji"
+	"sync"
+	"time"
+)
+
+// calendarSnapshot keeps the last fetched calendar to tell what changed on the next refresh.
+type calendarSnapshot struct {
+	mu          sync.Mutex
+	initialized bool
+	events      map[string]entity.CalendarEvent
+	// UTC days covered by the last refresh
+	from time.Time
+	to   time.Time
+}
+
+// eventChange is an event whose time moved, Previous holding the event as it was known before.
+type eventChange struct {
+	Previous entity.CalendarEvent
+	Current  entity.CalendarEvent
+}
+
+type calendarChanges struct {
+	Rescheduled []eventChange
+	Cancelled   []entity.CalendarEvent
+	Added       []entity.CalendarEvent
+}
+
+func newCalendarSnapshot() *calendarSnapshot {
+	return &calendarSnapshot{events: map[string]entity.CalendarEvent{}}
+}
+
+// update replaces the snapshot with the refreshed events, fetched for the days from the day of from to the day of to,
+// and returns what changed among the upcoming events. Nothing is reported on the first refresh.
+func (c *calendarSnapshot) update(events []entity.CalendarEvent, from time.Time, to time.Time, now time.Time) calendarChanges {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := map[string]entity.CalendarEvent{}
+	for _, e := range events {
+		current[e.Key()] = e
+	}
+
+	var changes calendarChanges
+	if c.initialized {
+		changes = diffCalendar(c.events, current, c.from, c.to, from, to, now)
+	}
+
+	c.events = current
+	c.initialized = true
+	c.from, c.to = coveredDays(events, from, to)
+	return changes
+}
+
+// coveredDays returns the first and the last UTC day whose events have been fetched. Providers publishing
+// a shorter range than the one requested, such as a weekly feed, cover up to the day of their last event.
+func coveredDays(events []entity.CalendarEvent, from time.Time, to time.Time) (time.Time, time.Time) {
+	first, last := utcDay(from), utcDay(to)
+	var latest time.Time
+	for _, e := range events {
+		if date, err := e.Time(); err == nil && date.After(latest) {
+			latest = date
+		}
+	}
+	if !latest.IsZero() && utcDay(latest).Before(last) {
+		last = utcDay(latest)
+	}
+	return first, last
+}
+
+// diffCalendar compares the events fetched for the days from previousFrom to previousTo with the ones fetched
+// for the days from from to to. Only the events added to days already covered before are reported as new,
+// the others belong to days fetched for the first time.
+func diffCalendar(previous map[string]entity.CalendarEvent, current map[string]entity.CalendarEvent,
+	previousFrom time.Time, previousTo time.Time, from time.Time, to time.Time, now time.Time) calendarChanges {
+	var changes calendarChanges
+
+	// only the upcoming events of the refreshed days can have been moved or dropped
+	currentFrom, currentTo := coveredDays(mapValues(current), from, to)
+	var missing []entity.CalendarEvent
+	for _, e := range eventsBetween(mapValues(previous), currentFrom, currentTo) {
+		date, err := e.Time()
+		if err != nil || date.Before(now) {
+			continue
+		}
+		updated, ok := current[e.Key()]
+		if !ok {
+			missing = append(missing, e)
+		} else if updated.Date != e.Date {
+			changes.Rescheduled = append(changes.Rescheduled, eventChange{e, updated})
+		}
+	}
+
+	var added []entity.CalendarEvent
+	for key, e := range current {
+		if _, ok := previous[key]; ok {
+			continue
+		}
+		date, err := e.Time()
+		if err != nil || date.Before(now) {
+			continue
+		}
+		added = append(added, e)
+	}
+
+	// an event moved to another day has a new key: pair it with the missing one by name and currency
+	for _, e := range missing {
+		moved := -1
+		for i, a := range added {
+			if a.Event == e.Event && a.Currency == e.Currency {
+				moved = i
+				break
+			}
+		}
+		if moved < 0 {
+			changes.Cancelled = append(changes.Cancelled, e)
+			continue
+		}
+		changes.Rescheduled = append(changes.Rescheduled, eventChange{e, added[moved]})
+		added = append(added[:moved], added[moved+1:]...)
+	}
+
+	for _, e := range added {
+		date, _ := e.Time()
+		day := utcDay(date)
+		if e.Impact == "High" && !day.Before(previousFrom) && !day.After(previousTo) {
+			changes.Added = append(changes.Added, e)
+		}
+	}
+	return changes
+}
+
+func mapValues(events map[string]entity.CalendarEvent) []entity.CalendarEvent {
+	values := make([]entity.CalendarEvent, 0, len(events))
+	for _, e := range events {
+		values = append(values, e)
+	}
+	return values
+}
+
+// forRecipient keeps the changes concerning events accepted by the recipient filter.
+func (c calendarChanges) forRecipient(recipient telegram.Recipient) calendarChanges {
+	var filtered calendarChanges
+	for _, change := range c.Rescheduled {
+		if acceptsEvent(recipient, change.Current) {
+			filtered.Rescheduled = append(filtered.Rescheduled, change)
+		}
+	}
+	for _, e := range c.Cancelled {
+		if acceptsEvent(recipient, e) {
+			filtered.Cancelled = append(filtered.Cancelled, e)
+		}
+	}
+	for _, e := range c.Added {
+		if acceptsEvent(recipient, e) {
+			filtered.Added = append(filtered.Added, e)
+		}
+	}
+	return filtered
+}
+
+func (c calendarChanges) empty() bool {
+	return len(c.Rescheduled) == 0 && len(c.Cancelled) == 0 && len(c.Added) == 0
+}
+
+func (s service) PrepareCalendarChangesMessage(changes calendarChanges, loc *time.Location) string {
+	message := emoji.Bell.String() + " Economic Calendar changes\n\n"
+	for _, change := range changes.Rescheduled {
+		message = message + emoji.AlarmClock.String() + "  RESCHEDULED: " + change.Current.Event + "  " + GetEmojiCountry(change.Current.Country) + "\n" +
+			"  " + formatEventDate(change.Previous, loc) + " -> " + formatEventDate(change.Current, loc) + "\n\n"
+	}
+	for _, e := range changes.Cancelled {
+		message = message + emoji.CrossMark.String() + "  CANCELLED: " + e.Event + "  " + GetEmojiCountry(e.Country) + "\n" +
+			"  " + formatEventDate(e, loc) + "\n\n"
+	}
+	for _, e := range changes.Added {
+		message = message + emoji.NewButton.String() + "  NEW: " + e.Event + "  " + GetEmojiCountry(e.Country) + "  " + GetEmojiSemaphore(e.Impact) + "\n" +
+			"  " + formatEventDate(e, loc) + "\n\n"
+	}
+	return message
+}
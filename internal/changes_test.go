@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"bot/entity"
+	"testing"
+	"time"
+)
+
+func TestCalendarSnapshotShiftedWindow(t *testing.T) {
+	now := time.Date(2025, 3, 3, 6, 0, 0, 0, time.UTC)
+	cpi := entity.CalendarEvent{Date: "2025-03-05 13:30:00", Country: "US", Event: "CPI m/m", Currency: "USD", Impact: "High"}
+	gdp := entity.CalendarEvent{Date: "2025-03-06 07:00:00", Country: "UK", Event: "GDP m/m", Currency: "GBP", Impact: "High"}
+	retail := entity.CalendarEvent{Date: "2025-03-07 13:30:00", Country: "US", Event: "Retail Sales m/m", Currency: "USD", Impact: "High"}
+
+	snapshot := newCalendarSnapshot()
+	snapshot.update([]entity.CalendarEvent{cpi, gdp, retail}, now, now.AddDate(0, 0, calendarRefreshDays), now)
+
+	// the next day the window covers one more day, with its events never seen before
+	now = now.AddDate(0, 0, 1)
+	newDay := entity.CalendarEvent{Date: "2025-03-11 13:30:00", Country: "US", Event: "PPI m/m", Currency: "USD", Impact: "High"}
+	added := entity.CalendarEvent{Date: "2025-03-06 15:00:00", Country: "US", Event: "Fed Chair Speaks", Currency: "USD", Impact: "High"}
+	moved := gdp
+	moved.Date = "2025-03-06 09:00:00"
+
+	changes := snapshot.update([]entity.CalendarEvent{cpi, moved, added, newDay}, now, now.AddDate(0, 0, calendarRefreshDays), now)
+
+	if len(changes.Added) != 1 || changes.Added[0].Key() != added.Key() {
+		t.Errorf("added = %v, want only %s", changes.Added, added.Event)
+	}
+	if len(changes.Rescheduled) != 1 || changes.Rescheduled[0].Current.Date != moved.Date {
+		t.Errorf("rescheduled = %v, want %s at %s", changes.Rescheduled, moved.Event, moved.Date)
+	}
+	if len(changes.Cancelled) != 1 || changes.Cancelled[0].Key() != retail.Key() {
+		t.Errorf("cancelled = %v, want only %s", changes.Cancelled, retail.Event)
+	}
+}
+
+func TestCalendarSnapshotWeeklyFeedRollover(t *testing.T) {
+	// a weekly feed returns the current week only, whatever the requested range
+	now := time.Date(2025, 3, 8, 12, 0, 0, 0, time.UTC)
+	friday := entity.CalendarEvent{Date: "2025-03-07 13:30:00", Country: "US", Event: "Non-Farm Employment Change", Currency: "USD", Impact: "High"}
+
+	snapshot := newCalendarSnapshot()
+	snapshot.update([]entity.CalendarEvent{friday}, now, now.AddDate(0, 0, calendarRefreshDays), now)
+
+	now = now.AddDate(0, 0, 1)
+	nextWeek := []entity.CalendarEvent{
+		{Date: "2025-03-12 12:30:00", Country: "US", Event: "CPI m/m", Currency: "USD", Impact: "High"},
+		{Date: "2025-03-13 12:30:00", Country: "US", Event: "PPI m/m", Currency: "USD", Impact: "High"},
+	}
+	changes := snapshot.update(nextWeek, now, now.AddDate(0, 0, calendarRefreshDays), now)
+
+	if !changes.empty() {
+		t.Errorf("changes = %+v, want none on the feed rollover", changes)
+	}
+}
@@ -0,0 +1,428 @@
+package internal
+
+import (
+	"bot/entity/telegram"
+	"errors"
+	"github.com/enescakir/emoji"
+	"log"
+	"strings"
+	"time"
+)
+
+// errUsage makes the registry answer with the command usage.
+var errUsage = errors.New("invalid arguments")
+
+// Command is a bot command. Its arguments are parsed by ParseArgs, when set, before calling Handle.
+type Command struct {
+	Name        string
+	Description string
+	// Descriptions translates Description by IETF language code for the Telegram menu, e.g. "it",
+	// as set by Localize
+	Descriptions map[string]string
+	// Scopes limits the Telegram menu entry to some BotCommandScope types, every chat when empty
+	Scopes []string
+	// Args documents the arguments in the /help listing, e.g. "YYYY-MM-DD"
+	Args string
+	// ParseArgs turns the text following the command into the value given to Handle
+	ParseArgs func(args string) (interface{}, error)
+	Handle    func(service Service, message telegram.Message, args interface{}) string
+	// Formatted tells that Handle answers in the parse mode of the service, plain text otherwise
+	Formatted bool
+}
+
+// CommandRegistry dispatches the commands addressed to the bot, in the order they were registered.
+type CommandRegistry struct {
+	botName  string
+	commands []Command
+	byName   map[string]Command
+}
+
+// NewCommandRegistry returns a registry with the bot commands. botName is the bot username, without @.
+func NewCommandRegistry(botName string) *CommandRegistry {
+	r := &CommandRegistry{botName: botName, byName: map[string]Command{}}
+	for _, command := range defaultCommands() {
+		r.Register(command)
+	}
+	r.Register(Command{
+		Name:        "help",
+		Description: "List the available commands",
+		Handle: func(service Service, message telegram.Message, args interface{}) string {
+			return r.Help()
+		},
+	})
+	return r
+}
+
+// Register adds a command, replacing the one with the same name.
+func (r *CommandRegistry) Register(command Command) {
+	if _, ok := r.byName[command.Name]; ok {
+		for i := range r.commands {
+			if r.commands[i].Name == command.Name {
+				r.commands[i] = command
+			}
+		}
+	} else {
+		r.commands = append(r.commands, command)
+	}
+	r.byName[command.Name] = command
+}
+
+// Localize sets the translated descriptions of the commands, by language then command name.
+func (r *CommandRegistry) Localize(descriptions map[string]map[string]string) {
+	for i := range r.commands {
+		command := &r.commands[i]
+		for language, translated := range descriptions {
+			description, ok := translated[command.Name]
+			if !ok {
+				continue
+			}
+			if command.Descriptions == nil {
+				command.Descriptions = map[string]string{}
+			}
+			command.Descriptions[language] = description
+		}
+		r.byName[command.Name] = *command
+	}
+}
+
+func (r *CommandRegistry) Commands() []Command {
+	commands := make([]Command, len(r.commands))
+	copy(commands, r.commands)
+	return commands
+}
+
+// Dispatch runs the command of a message and returns the answer with its parse mode. Nothing is answered
+// to text that is not a command, to commands addressed to another bot, nor when the command answers with an empty text.
+func (r *CommandRegistry) Dispatch(service Service, message telegram.Message) (string, string, bool) {
+	name, args, ok := parseCommand(message.Text, r.botName)
+	if !ok {
+		return "", "", false
+	}
+
+	command, found := r.byName[name]
+	if !found {
+		return service.PrepareCommandNotFoundMessageToTelegramChat(), "", true
+	}
+
+	var parsed interface{}
+	if command.ParseArgs != nil {
+		value, err := command.ParseArgs(args)
+		if err != nil {
+			return emoji.CrossMark.String() + " Usage: " + commandUsage(command), "", true
+		}
+		parsed = value
+	}
+	if command.Formatted {
+		return command.Handle(service, message, parsed), service.ParseMode(), true
+	}
+	return command.Handle(service, message, parsed), "", true
+}
+
+// Help lists the registered commands with their arguments and description.
+func (r *CommandRegistry) Help() string {
+	message := emoji.Eyes.String() + " Available commands:\n\n"
+	for _, command := range r.commands {
+		message = message + commandUsage(command) + " - " + command.Description + "\n"
+	}
+	return message
+}
+
+func commandUsage(command Command) string {
+	if command.Args == "" {
+		return "/" + command.Name
+	}
+	return "/" + command.Name + " " + command.Args
+}
+
+// parseCommand splits "/name@BotName args" into the lowercase name and the arguments. False is returned
+// for text that is not a command, such as service messages, and for commands addressed to another bot.
+func parseCommand(text string, botName string) (string, string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+
+	name, args, _ := strings.Cut(text, " ")
+	name, target, addressed := strings.Cut(strings.TrimPrefix(name, "/"), "@")
+	if addressed && botName != "" && !strings.EqualFold(target, botName) {
+		return "", "", false
+	}
+	return strings.ToLower(name), strings.TrimSpace(args), true
+}
+
+// Telegram BotCommandScope types the commands are published to
+const (
+	botCommandScopeDefault = "default"
+	botCommandScopePrivate = "all_private_chats"
+	botCommandScopeGroups  = "all_group_chats"
+)
+
+// BotCommands returns the commands shown in the Telegram menu of the scope, described in language
+// when translated, in English otherwise.
+func (r *CommandRegistry) BotCommands(scope string, language string) []telegram.BotCommand {
+	var botCommands []telegram.BotCommand
+	for _, command := range r.commands {
+		if len(command.Scopes) > 0 && !containsString(command.Scopes, scope) {
+			continue
+		}
+		description := command.Description
+		if translated, ok := command.Descriptions[language]; ok {
+			description = translated
+		}
+		botCommands = append(botCommands, telegram.BotCommand{Command: command.Name, Description: description})
+	}
+	return botCommands
+}
+
+// Publish registers the commands with Telegram, for every scope in English and in each of languages
+// with translated descriptions, so the menu button lists them.
+func (r *CommandRegistry) Publish(service Service, languages []string) {
+	for _, language := range languages {
+		if !r.translated(language) {
+			log.Printf("no command descriptions in %s, the menu falls back to English", language)
+		}
+	}
+	for _, scope := range []string{botCommandScopeDefault, botCommandScopePrivate, botCommandScopeGroups} {
+		for _, language := range append([]string{""}, languages...) {
+			if language != "" && !r.translated(language) {
+				continue
+			}
+			telegramResponseBody, err := service.SetMyCommands(r.BotCommands(scope, language),
+				telegram.BotCommandScope{Type: scope}, language)
+			if err != nil {
+				log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
+			}
+		}
+	}
+}
+
+// translated tells whether a command has a description in language.
+func (r *CommandRegistry) translated(language string) bool {
+	for _, command := range r.commands {
+		if _, ok := command.Descriptions[language]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultCommands() []Command {
+	return []Command{
+		{
+			Name:        "start",
+			Description: "Welcome message",
+			// Telegram sends /start when a user opens the private chat
+			Scopes:    []string{botCommandScopePrivate},
+			Formatted: true,
+			Handle: func(service Service, message telegram.Message, args interface{}) string {
+				return service.PrepareStartMessageToTelegramChat()
+			},
+		},
+		{
+			Name:        "subscribe",
+			Description: "Receive the notifications in this chat",
+			Args:        "[timezone]",
+			ParseArgs: func(args string) (interface{}, error) {
+				if args == "" {
+					return "", nil
+				}
+				if _, err := time.LoadLocation(args); err != nil {
+					return nil, errUsage
+				}
+				return args, nil
+			},
+			Handle: func(service Service, message telegram.Message, args interface{}) string {
+				subscribed, err := service.Subscribe(telegram.Recipient{
+					ChatId:          message.Chat.Id,
+					MessageThreadId: message.MessageThreadId,
+					Timezone:        args.(string),
+				})
+				if err != nil {
+					log.Printf("could not subscribe chat %d: %s", message.Chat.Id, err.Error())
+					return emoji.CrossMark.String() + " Could not subscribe, try again later"
+				}
+				if !subscribed {
+					return emoji.CheckMarkButton.String() + " This chat is already subscribed"
+				}
+				return emoji.CheckMarkButton.String() + " Subscribed! Notifications will be sent to this chat\n\n" +
+					service.PrepareRecipientFilterMessage(service.GetRecipient(message.Chat.Id, message.MessageThreadId))
+			},
+		},
+		{
+			Name:        "unsubscribe",
+			Description: "Stop the notifications in this chat",
+			Handle: func(service Service, message telegram.Message, args interface{}) string {
+				if denied := settingsDenied(service, message.Chat, message.From.Id); denied != "" {
+					return emoji.CrossMark.String() + " " + denied
+				}
+				unsubscribed, err := service.Unsubscribe(message.Chat.Id, message.MessageThreadId)
+				if err != nil {
+					log.Printf("could not unsubscribe chat %d: %s", message.Chat.Id, err.Error())
+					return emoji.CrossMark.String() + " Could not unsubscribe, try again later"
+				}
+				if !unsubscribed {
+					return emoji.CrossMark.String() + " This chat is not subscribed"
+				}
+				return emoji.WavingHand.String() + " Unsubscribed, no more notifications in this chat"
+			},
+		},
+		{
+			Name:        "settings",
+			Description: "Change currencies, impact and delivery time with buttons",
+			Handle: func(service Service, message telegram.Message, args interface{}) string {
+				recipient, ok := service.GetSubscribedRecipient(message.Chat.Id, message.MessageThreadId)
+				if !ok {
+					return emoji.CrossMark.String() + " This chat is not subscribed, use /subscribe first"
+				}
+				telegramResponseBody, err := service.SendMenuToTelegramChat(message.Chat.Id, message.MessageThreadId,
+					service.PrepareSettingsMessage(recipient), service.PrepareSettingsKeyboard(recipient))
+				if err != nil {
+					log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
+				}
+				// the menu is the answer
+				return ""
+			},
+		},
+		{
+			Name:        "today",
+			Description: "Today's economic calendar",
+			Formatted:   true,
+			Handle: func(service Service, message telegram.Message, args interface{}) string {
+				recipient := service.GetRecipient(message.Chat.Id, message.MessageThreadId)
+				return service.PrepareCachedCalendarMessage(recipient, time.Now().In(loadLocation(recipient.Timezone)))
+			},
+		},
+		{
+			Name:        "tomorrow",
+			Description: "Tomorrow's economic calendar",
+			Formatted:   true,
+			Handle: func(service Service, message telegram.Message, args interface{}) string {
+				recipient := service.GetRecipient(message.Chat.Id, message.MessageThreadId)
+				return service.PrepareCachedCalendarMessage(recipient, time.Now().In(loadLocation(recipient.Timezone)).AddDate(0, 0, 1))
+			},
+		},
+		{
+			Name:        "calendar",
+			Description: "Economic calendar of a day",
+			Args:        "YYYY-MM-DD",
+			ParseArgs: func(args string) (interface{}, error) {
+				// the date is resolved in the chat timezone by Handle
+				if _, err := time.Parse("2006-01-02", args); err != nil {
+					return nil, errUsage
+				}
+				return args, nil
+			},
+			Formatted: true,
+			Handle: func(service Service, message telegram.Message, args interface{}) string {
+				recipient := service.GetRecipient(message.Chat.Id, message.MessageThreadId)
+				day, _ := time.ParseInLocation("2006-01-02", args.(string), loadLocation(recipient.Timezone))
+				return service.PrepareCachedCalendarMessage(recipient, day)
+			},
+		},
+		{
+			Name:        "centralbanks",
+			Description: "Next central bank meetings",
+			Handle: func(service Service, message telegram.Message, args interface{}) string {
+				recipient := service.GetRecipient(message.Chat.Id, message.MessageThreadId)
+				return service.PrepareCentralBanksMessage(loadLocation(recipient.Timezone))
+			},
+		},
+		{
+			Name:        "history",
+			Description: "Last released values of an indicator",
+			Args:        "[currency] indicator",
+			ParseArgs:   parseRequiredArgs,
+			Handle: func(service Service, message telegram.Message, args interface{}) string {
+				recipient := service.GetRecipient(message.Chat.Id, message.MessageThreadId)
+				return service.PrepareHistoryMessage(args.(string), loadLocation(recipient.Timezone))
+			},
+		},
+		{
+			Name:        "impact",
+			Description: "XAUUSD reaction on the release days of an indicator",
+			Args:        "[currency] indicator",
+			ParseArgs:   parseRequiredArgs,
+			Handle: func(service Service, message telegram.Message, args interface{}) string {
+				return service.PrepareXauImpactMessage(args.(string))
+			},
+		},
+		{
+			Name:        "currencies",
+			Description: "Show or set the currencies notified to this chat",
+			Args:        "[USD,EUR,...]",
+			ParseArgs: func(args string) (interface{}, error) {
+				if args == "" {
+					return []string(nil), nil
+				}
+				currencies, ok := parseCurrencies(args)
+				if !ok {
+					return nil, errUsage
+				}
+				return currencies, nil
+			},
+			Handle: func(service Service, message telegram.Message, args interface{}) string {
+				currencies := args.([]string)
+				if currencies == nil {
+					recipient, ok := service.GetSubscribedRecipient(message.Chat.Id, message.MessageThreadId)
+					if !ok {
+						return emoji.CrossMark.String() + " This chat is not subscribed, use /subscribe first"
+					}
+					return service.PrepareRecipientFilterMessage(recipient)
+				}
+				return updateRecipientFilter(service, message, func(recipient *telegram.Recipient) {
+					recipient.Currencies = currencies
+				})
+			},
+		},
+		{
+			Name:        "minimpact",
+			Description: "Set the minimum impact notified to this chat",
+			Args:        "Low|Medium|High",
+			ParseArgs: func(args string) (interface{}, error) {
+				impact, ok := parseImpact(args)
+				if !ok {
+					return nil, errUsage
+				}
+				return impact, nil
+			},
+			Handle: func(service Service, message telegram.Message, args interface{}) string {
+				return updateRecipientFilter(service, message, func(recipient *telegram.Recipient) {
+					recipient.MinImpact = args.(string)
+				})
+			},
+		},
+	}
+}
+
+func parseRequiredArgs(args string) (interface{}, error) {
+	if args == "" {
+		return nil, errUsage
+	}
+	return args, nil
+}
+
+// updateRecipientFilter applies update to the filter of the chat and answers with the resulting filter.
+// In groups only the administrators can change the filter.
+func updateRecipientFilter(service Service, message telegram.Message, update func(recipient *telegram.Recipient)) string {
+	if denied := settingsDenied(service, message.Chat, message.From.Id); denied != "" {
+		return emoji.CrossMark.String() + " " + denied
+	}
+	recipient, err := service.UpdateRecipient(message.Chat.Id, message.MessageThreadId, update)
+	if errors.Is(err, ErrRecipientNotFound) {
+		return emoji.CrossMark.String() + " This chat is not subscribed, use /subscribe first"
+	}
+	if err != nil {
+		log.Printf("could not update recipient %d: %s", message.Chat.Id, err.Error())
+		return emoji.CrossMark.String() + " Could not save the filter, try again later"
+	}
+	return service.PrepareRecipientFilterMessage(recipient)
+}
@@ -0,0 +1,235 @@
+package internal
+
+import (
+	"bot/conf"
+	"bot/entity/telegram"
+	"testing"
+)
+
+// fakeService answers the calls of the commands and of the webhook without Telegram, recording the sent messages.
+// The methods not overridden panic.
+type fakeService struct {
+	Service
+	sent *[]string
+	// status of the chat members by user id
+	members map[int]string
+	// chats whose recipient was updated or removed
+	changed *[]int
+}
+
+func newFakeService() fakeService {
+	return fakeService{sent: &[]string{}, members: map[int]string{}, changed: &[]int{}}
+}
+
+func (f fakeService) PrepareCommandNotFoundMessageToTelegramChat() string {
+	return "command not found"
+}
+
+func (f fakeService) ParseMode() string {
+	return ParseModeMarkdownV2
+}
+
+func (f fakeService) SendTextToTelegramChat(chatId int, messageThreadId int, text string, parseMode string) ([]int, string, error) {
+	*f.sent = append(*f.sent, text)
+	return []int{len(*f.sent)}, "", nil
+}
+
+func (f fakeService) GetChatMember(chatId int, userId int) (telegram.ChatMember, error) {
+	return telegram.ChatMember{Status: f.members[userId], User: telegram.User{Id: userId}}, nil
+}
+
+func (f fakeService) UpdateRecipient(chatId int, messageThreadId int, update func(recipient *telegram.Recipient)) (telegram.Recipient, error) {
+	recipient := telegram.Recipient{ChatId: chatId, MessageThreadId: messageThreadId}
+	update(&recipient)
+	*f.changed = append(*f.changed, chatId)
+	return recipient, nil
+}
+
+func (f fakeService) Unsubscribe(chatId int, messageThreadId int) (bool, error) {
+	*f.changed = append(*f.changed, chatId)
+	return true, nil
+}
+
+func (f fakeService) PrepareRecipientFilterMessage(recipient telegram.Recipient) string {
+	return "filter"
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text    string
+		botName string
+		name    string
+		args    string
+		ok      bool
+	}{
+		{"/today", "EconomicBot", "today", "", true},
+		{"  /Today  ", "EconomicBot", "today", "", true},
+		{"/calendar 2025-03-12", "EconomicBot", "calendar", "2025-03-12", true},
+		{"/history   USD CPI  ", "EconomicBot", "history", "USD CPI", true},
+		{"/today@EconomicBot", "EconomicBot", "today", "", true},
+		{"/today@economicbot", "EconomicBot", "today", "", true},
+		{"/calendar@EconomicBot 2025-03-12", "EconomicBot", "calendar", "2025-03-12", true},
+		{"/today@OtherBot", "EconomicBot", "", "", false},
+		{"/calendar@OtherBot 2025-03-12", "EconomicBot", "", "", false},
+		// without a configured name every bot is this one
+		{"/today@OtherBot", "", "today", "", true},
+		{"hello there", "EconomicBot", "", "", false},
+		{"", "EconomicBot", "", "", false},
+		{"📅 USD CPI m/m", "EconomicBot", "", "", false},
+	}
+	for _, tt := range tests {
+		name, args, ok := parseCommand(tt.text, tt.botName)
+		if name != tt.name || args != tt.args || ok != tt.ok {
+			t.Errorf("parseCommand(%q, %q) = %q, %q, %t, want %q, %q, %t",
+				tt.text, tt.botName, name, args, ok, tt.name, tt.args, tt.ok)
+		}
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	r := NewCommandRegistry("EconomicBot")
+	r.Register(Command{
+		Name:      "echo",
+		Args:      "text",
+		ParseArgs: parseRequiredArgs,
+		Handle: func(service Service, message telegram.Message, args interface{}) string {
+			return args.(string)
+		},
+	})
+	r.Register(Command{
+		Name:      "bold",
+		Formatted: true,
+		Handle: func(service Service, message telegram.Message, args interface{}) string {
+			return "*bold*"
+		},
+	})
+	r.Register(Command{
+		Name: "silent",
+		Handle: func(service Service, message telegram.Message, args interface{}) string {
+			return ""
+		},
+	})
+
+	tests := []struct {
+		text      string
+		message   string
+		parseMode string
+		ok        bool
+	}{
+		{"/echo hello", "hello", "", true},
+		{"/echo@EconomicBot hello", "hello", "", true},
+		{"/echo@OtherBot hello", "", "", false},
+		{"/echo", "❌ Usage: /echo text", "", true},
+		{"/calendar tomorrow", "❌ Usage: /calendar YYYY-MM-DD", "", true},
+		{"/minimpact severe", "❌ Usage: /minimpact Low|Medium|High", "", true},
+		{"/history", "❌ Usage: /history [currency] indicator", "", true},
+		{"/bold", "*bold*", ParseModeMarkdownV2, true},
+		{"/silent", "", "", true},
+		{"/unknown", "command not found", "", true},
+		{"/", "command not found", "", true},
+		{"hello there", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		message, parseMode, ok := r.Dispatch(newFakeService(), telegram.Message{Text: tt.text})
+		if message != tt.message || parseMode != tt.parseMode || ok != tt.ok {
+			t.Errorf("Dispatch(%q) = %q, %q, %t, want %q, %q, %t", tt.text, message, parseMode, ok, tt.message, tt.parseMode, tt.ok)
+		}
+	}
+}
+
+func TestLocalizedBotCommands(t *testing.T) {
+	descriptions, err := conf.LoadCommandDescriptions("../command_descriptions.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewCommandRegistry("EconomicBot")
+	r.Localize(descriptions)
+
+	for language, translated := range descriptions {
+		for name := range translated {
+			if _, ok := r.byName[name]; !ok {
+				t.Errorf("%s description of the unknown command %s", language, name)
+			}
+		}
+		for _, command := range r.BotCommands(botCommandScopeDefault, language) {
+			if command.Description != translated[command.Command] {
+				t.Errorf("%s description of %s = %q, want %q", language, command.Command, command.Description, translated[command.Command])
+			}
+		}
+	}
+
+	// languages without translations are described in English
+	for _, command := range r.BotCommands(botCommandScopeDefault, "de") {
+		if command.Description != r.byName[command.Command].Description {
+			t.Errorf("de description of %s = %q, want the English one", command.Command, command.Description)
+		}
+	}
+	if !r.translated("it") || r.translated("de") {
+		t.Errorf("translated(it), translated(de) = %t, %t, want true, false", r.translated("it"), r.translated("de"))
+	}
+}
+
+func TestGroupSettingsCommandsAdminOnly(t *testing.T) {
+	r := NewCommandRegistry("EconomicBot")
+	const admin, member = 1, 2
+
+	tests := []struct {
+		text     string
+		chatType string
+		userId   int
+		changed  bool
+	}{
+		{"/currencies USD,EUR", "supergroup", member, false},
+		{"/minimpact High", "group", member, false},
+		{"/unsubscribe", "supergroup", member, false},
+		{"/currencies USD,EUR", "supergroup", admin, true},
+		{"/minimpact High", "group", admin, true},
+		{"/unsubscribe", "supergroup", admin, true},
+		// in private chats the user owns the settings
+		{"/minimpact High", "private", member, true},
+		{"/unsubscribe", "private", member, true},
+	}
+	for _, tt := range tests {
+		service := newFakeService()
+		service.members[admin] = "administrator"
+		service.members[member] = "member"
+		message := telegram.Message{Text: tt.text, From: telegram.User{Id: tt.userId}, Chat: telegram.Chat{Id: -100, Type: tt.chatType}}
+
+		answer, _, _ := r.Dispatch(service, message)
+		if changed := len(*service.changed) > 0; changed != tt.changed {
+			t.Errorf("%s by user %d in %s: changed = %t, want %t (answer %q)", tt.text, tt.userId, tt.chatType, changed, tt.changed, answer)
+		}
+		if !tt.changed && answer != "❌ Only the group administrators can change the settings" {
+			t.Errorf("%s by user %d in %s: answer = %q", tt.text, tt.userId, tt.chatType, answer)
+		}
+	}
+}
+
+func TestSettingsDenied(t *testing.T) {
+	service := newFakeService()
+	service.members[1] = "creator"
+	service.members[2] = "administrator"
+	service.members[3] = "member"
+	service.members[4] = "restricted"
+
+	tests := []struct {
+		chatType string
+		userId   int
+		allowed  bool
+	}{
+		{"group", 1, true},
+		{"supergroup", 2, true},
+		{"supergroup", 3, false},
+		{"group", 4, false},
+		{"supergroup", 5, false},
+		{"private", 3, true},
+		{"channel", 3, true},
+	}
+	for _, tt := range tests {
+		denied := settingsDenied(service, telegram.Chat{Id: -100, Type: tt.chatType}, tt.userId)
+		if (denied == "") != tt.allowed {
+			t.Errorf("settingsDenied(%s, %d) = %q, want allowed %t", tt.chatType, tt.userId, denied, tt.allowed)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"bot/entity"
+	"bot/entity/telegram"
+	"sort"
+	"strings"
+)
+
+// Filter used for recipients not declaring their own currencies or minimum impact.
+var defaultCurrencies = []string{"EUR", "GBP", "USD", "JPY"}
+
+const defaultMinImpact = "High"
+
+func recipientCurrencies(r telegram.Recipient) []string {
+	if len(r.Currencies) == 0 {
+		return defaultCurrencies
+	}
+	return r.Currencies
+}
+
+func recipientMinImpact(r telegram.Recipient) string {
+	if r.MinImpact == "" {
+		return defaultMinImpact
+	}
+	return r.MinImpact
+}
+
+func impactRank(impact string) int {
+	switch impact {
+	case "Low":
+		return 1
+	case "Medium":
+		return 2
+	case "High":
+		return 3
+	default:
+		return 0
+	}
+}
+
+// acceptsEvent tells whether an event passes the currency and minimum impact filter of a recipient.
+func acceptsEvent(r telegram.Recipient, e entity.CalendarEvent) bool {
+	if impactRank(e.Impact) < impactRank(recipientMinImpact(r)) {
+		return false
+	}
+	return acceptsCurrency(r, e.Currency)
+}
+
+func acceptsCurrency(r telegram.Recipient, currency string) bool {
+	for _, c := range recipientCurrencies(r) {
+		if strings.EqualFold(c, currency) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedByAny keeps the events passing the filter of at least one recipient.
+func acceptedByAny(recipients []telegram.Recipient, events []entity.CalendarEvent) []entity.CalendarEvent {
+	var accepted []entity.CalendarEvent
+	for _, e := range events {
+		for _, recipient := range recipients {
+			if acceptsEvent(recipient, e) {
+				accepted = append(accepted, e)
+				break
+			}
+		}
+	}
+	return accepted
+}
+
+// filterKey identifies recipients sharing timezone and filter, which receive the same rendered messages.
+func filterKey(r telegram.Recipient) string {
+	currencies := make([]string, 0, len(recipientCurrencies(r)))
+	for _, currency := range recipientCurrencies(r) {
+		currencies = append(currencies, strings.ToUpper(currency))
+	}
+	sort.Strings(currencies)
+	return r.Timezone + "|" + strings.Join(currencies, ",") + "|" + recipientMinImpact(r)
+}
+
+// parseCurrencies reads a list of currency codes separated by commas or spaces.
+func parseCurrencies(args string) ([]string, bool) {
+	var currencies []string
+	for _, field := range strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if len(field) != 3 || strings.IndexFunc(field, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+		}) >= 0 {
+			return nil, false
+		}
+		currencies = append(currencies, strings.ToUpper(field))
+	}
+	return currencies, len(currencies) > 0
+}
+
+// parseImpact accepts Low, Medium and High in any case.
+func parseImpact(args string) (string, bool) {
+	impact := normalizeImpact(args)
+	return impact, impactRank(impact) > 0
+}
+
+// acceptsMarket tells whether the recipient follows the currency of a market (US, UK, EU, JP).
+func acceptsMarket(r telegram.Recipient, market string) bool {
+	for _, c := range recipientCurrencies(r) {
+		if countryForCurrency(c) == market {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"bot/entity"
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// forexFactoryCalendarProvider reads the free weekly calendar feeds (ff_calendar_thisweek.json/.xml).
+// The source can be either an URL or a local file path.
+type forexFactoryCalendarProvider struct {
+	source string
+	// location of the date and time of the XML feed; the JSON feed carries its own offset
+	location *time.Location
+}
+
+type forexFactoryJsonEvent struct {
+	Title    string `json:"title"`
+	Country  string `json:"country"`
+	Date     string `json:"date"`
+	Impact   string `json:"impact"`
+	Forecast string `json:"forecast"`
+	Previous string `json:"previous"`
+}
+
+type forexFactoryXmlFeed struct {
+	Events []forexFactoryXmlEvent `xml:"event"`
+}
+
+type forexFactoryXmlEvent struct {
+	Title    string `xml:"title"`
+	Country  string `xml:"country"`
+	Date     string `xml:"date"`
+	Time     string `xml:"time"`
+	Impact   string `xml:"impact"`
+	Forecast string `xml:"forecast"`
+	Previous string `xml:"previous"`
+}
+
+func (p forexFactoryCalendarProvider) GetEvents(from time.Time, to time.Time) ([]entity.CalendarEvent, error) {
+	body, err := openCalendarSource(p.source)
+	if err != nil {
+		log.Printf("error while opening ForexFactory feed %s", err.Error())
+		return nil, err
+	}
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			log.Printf("error when closing ForexFactory feed: %s", err.Error())
+		}
+	}(body)
+
+	events, err := parseForexFactoryFeed(body, p.location)
+	if err != nil {
+		log.Printf("error while parsing ForexFactory feed %s", err.Error())
+		return nil, err
+	}
+
+	return eventsBetween(events, from, to), nil
+}
+
+// parseForexFactoryFeed parses both the JSON and the XML flavour of the feed, telling them apart by the first character.
+func parseForexFactoryFeed(r io.Reader, location *time.Location) ([]entity.CalendarEvent, error) {
+	reader := bufio.NewReader(r)
+	for {
+		b, err := reader.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		// skip whitespace and the UTF-8 byte order mark
+		if strings.IndexByte(" \t\r\n\xef\xbb\xbf", b[0]) >= 0 {
+			_, _ = reader.Discard(1)
+			continue
+		}
+		if b[0] == '<' {
+			return parseForexFactoryXml(reader, location)
+		}
+		return parseForexFactoryJson(reader)
+	}
+}
+
+func parseForexFactoryJson(r io.Reader) ([]entity.CalendarEvent, error) {
+	var feed []forexFactoryJsonEvent
+	if err := json.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	var events []entity.CalendarEvent
+	for _, e := range feed {
+		date, err := time.Parse(time.RFC3339, e.Date)
+		if err != nil {
+			log.Printf("Error parsing ForexFactory date %s: %v", e.Date, err)
+			continue
+		}
+		events = append(events, newForexFactoryEvent(date, e.Title, e.Country, e.Impact, e.Forecast, e.Previous))
+	}
+	return events, nil
+}
+
+func parseForexFactoryXml(r io.Reader, location *time.Location) ([]entity.CalendarEvent, error) {
+	var feed forexFactoryXmlFeed
+	decoder := xml.NewDecoder(r)
+	// the feed is declared as windows-1252, which is ASCII for the fields we read
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := decoder.Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	var events []entity.CalendarEvent
+	for _, e := range feed.Events {
+		day, err := time.ParseInLocation("01-02-2006", strings.TrimSpace(e.Date), location)
+		if err != nil {
+			log.Printf("Error parsing ForexFactory date %s: %v", e.Date, err)
+			continue
+		}
+		// "All Day", "Tentative" and similar values are kept at midnight
+		date := day
+		if clock, err := time.Parse("3:04pm", strings.TrimSpace(e.Time)); err == nil {
+			date = time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, location)
+		}
+		events = append(events, newForexFactoryEvent(date, e.Title, e.Country, e.Impact, e.Forecast, e.Previous))
+	}
+	return events, nil
+}
+
+// newForexFactoryEvent builds a CalendarEvent; the feed "country" field is actually the currency.
+func newForexFactoryEvent(date time.Time, title string, currency string, impact string, forecast string, previous string) entity.CalendarEvent {
+	event := entity.CalendarEvent{
+		Date:     date.UTC().Format(entity.CalendarEventDateLayout),
+		Country:  countryForCurrency(currency),
+		Event:    strings.TrimSpace(title),
+		Currency: strings.ToUpper(strings.TrimSpace(currency)),
+		Impact:   normalizeImpact(impact),
+	}
+	setEventValue(&event, &event.Forecast, forecast)
+	setEventValue(&event, &event.Previous, previous)
+	return event
+}
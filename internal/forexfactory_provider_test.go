@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"bot/entity"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseForexFactoryFixture(t *testing.T, name string, location *time.Location) []entity.CalendarEvent {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	events, err := parseForexFactoryFeed(f, location)
+	if err != nil {
+		t.Fatalf("parseForexFactoryFeed(%s): %v", name, err)
+	}
+	return events
+}
+
+// findEvent returns the first event named title.
+func findEvent(t *testing.T, events []entity.CalendarEvent, title string) entity.CalendarEvent {
+	t.Helper()
+	for _, e := range events {
+		if e.Event == title {
+			return e
+		}
+	}
+	t.Fatalf("event %q not found", title)
+	return entity.CalendarEvent{}
+}
+
+func formatValue(value *float64) string {
+	return FormatEventValue(value, "")
+}
+
+func TestParseForexFactoryJson(t *testing.T) {
+	events := parseForexFactoryFixture(t, "ff_calendar_thisweek.json", time.UTC)
+	if len(events) != 14 {
+		t.Fatalf("got %d events, want 14", len(events))
+	}
+
+	tests := []struct {
+		title    string
+		date     string
+		country  string
+		currency string
+		impact   string
+		forecast string
+		previous string
+		unit     string
+	}{
+		// the offset of the feed is converted to UTC
+		{"Core CPI m/m", "2025-03-12 12:30:00", "US", "USD", "High", "0.3", "0.4", "%"},
+		{"JOLTS Job Openings", "2025-03-11 14:00:00", "US", "USD", "Medium", "7.63", "7.6", "M"},
+		{"Unemployment Claims", "2025-03-13 12:30:00", "US", "USD", "High", "226", "221", "K"},
+		{"GDP m/m", "2025-03-14 07:00:00", "UK", "GBP", "High", "0.1", "0.4", "%"},
+		{"French Gov Budget Balance", "2025-03-10 07:45:00", "EU", "EUR", "Low", "-", "-173.3", "B"},
+		{"Prelim UoM Consumer Sentiment", "2025-03-14 14:00:00", "US", "USD", "High", "63.2", "64.7", ""},
+		// all day events are at the local midnight of the feed
+		{"Bank Holiday", "2025-03-10 04:00:00", "JP", "JPY", "Holiday", "-", "-", ""},
+		{"BOC Rate Statement", "2025-03-12 13:45:00", "CA", "CAD", "High", "-", "-", ""},
+		{"G7 Meetings", "2025-03-14 04:00:00", "", "ALL", "Medium", "-", "-", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			e := findEvent(t, events, tt.title)
+			if e.Date != tt.date || e.Country != tt.country || e.Currency != tt.currency || e.Impact != tt.impact {
+				t.Errorf("got %s %s %s %s, want %s %s %s %s", e.Date, e.Country, e.Currency, e.Impact,
+					tt.date, tt.country, tt.currency, tt.impact)
+			}
+			if formatValue(e.Forecast) != tt.forecast || formatValue(e.Previous) != tt.previous || e.Unit != tt.unit {
+				t.Errorf("got forecast %s previous %s unit %q, want %s %s %q",
+					formatValue(e.Forecast), formatValue(e.Previous), e.Unit, tt.forecast, tt.previous, tt.unit)
+			}
+			if e.Actual != nil {
+				t.Errorf("got actual %s, want none", formatValue(e.Actual))
+			}
+		})
+	}
+}
+
+func TestParseForexFactoryXml(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no timezone database")
+	}
+
+	tests := []struct {
+		location *time.Location
+		title    string
+		date     string
+		impact   string
+		forecast string
+		previous string
+	}{
+		{time.UTC, "Core CPI m/m", "2025-03-12 12:30:00", "High", "0.3", "0.4"},
+		{time.UTC, "French Gov Budget Balance", "2025-03-10 07:45:00", "Low", "-", "-173.3"},
+		{time.UTC, "Unemployment Claims", "2025-03-13 12:30:00", "High", "226", "221"},
+		// all day and tentative times are kept at the local midnight
+		{time.UTC, "Bank Holiday", "2025-03-10 00:00:00", "Holiday", "-", "-"},
+		{time.UTC, "Eurogroup Meetings", "2025-03-10 00:00:00", "Medium", "-", "-"},
+		// the feed times are read in the configured timezone, daylight saving time started on March 9
+		{newYork, "Core CPI m/m", "2025-03-12 16:30:00", "High", "0.3", "0.4"},
+		{newYork, "Bank Holiday", "2025-03-10 04:00:00", "Holiday", "-", "-"},
+		{newYork, "Prelim UoM Consumer Sentiment", "2025-03-14 18:00:00", "High", "63.2", "64.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.location.String()+"/"+tt.title, func(t *testing.T) {
+			e := findEvent(t, parseForexFactoryFixture(t, "ff_calendar_thisweek.xml", tt.location), tt.title)
+			if e.Date != tt.date || e.Impact != tt.impact {
+				t.Errorf("got %s %s, want %s %s", e.Date, e.Impact, tt.date, tt.impact)
+			}
+			if formatValue(e.Forecast) != tt.forecast || formatValue(e.Previous) != tt.previous {
+				t.Errorf("got forecast %s previous %s, want %s %s",
+					formatValue(e.Forecast), formatValue(e.Previous), tt.forecast, tt.previous)
+			}
+		})
+	}
+}
+
+func TestParseForexFactoryFeedByteOrderMark(t *testing.T) {
+	feed := "\xef\xbb\xbf\n" + `[{"title":"CPI y/y","country":"USD","date":"2025-03-12T08:30:00-04:00","impact":"High","forecast":"2.9%","previous":"3.0%"}]`
+	events, err := parseForexFactoryFeed(strings.NewReader(feed), time.UTC)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("got %v %v, want one event", events, err)
+	}
+}
+
+func TestNormalizeImpact(t *testing.T) {
+	tests := map[string]string{
+		"High":         "High",
+		" high ":       "High",
+		"Medium":       "Medium",
+		"ora":          "Medium",
+		"Low":          "Low",
+		"yel":          "Low",
+		"Non-Economic": "Low",
+		"Holiday":      "Holiday",
+		"Unknown":      "Unknown",
+	}
+	for impact, want := range tests {
+		if got := normalizeImpact(impact); got != want {
+			t.Errorf("normalizeImpact(%q) = %q, want %q", impact, got, want)
+		}
+	}
+}
+
+func TestForexFactoryProviderOffline(t *testing.T) {
+	provider := forexFactoryCalendarProvider{source: "testdata/ff_calendar_thisweek.json", location: time.UTC}
+	events, err := provider.GetEvents(time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 4 {
+		t.Errorf("got %d events on 2025-03-12, want 4", len(events))
+	}
+}
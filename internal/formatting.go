@@ -0,0 +1,87 @@
+package internal
+
+import "strings"
+
+// Telegram parse modes selected by the parse_mode config, plain text when empty
+const (
+	ParseModeHTML       = "HTML"
+	ParseModeMarkdownV2 = "MarkdownV2"
+)
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// the characters to escape in MarkdownV2 text, and in code entities
+const (
+	markdownV2Special     = "_*[]()~`>#+-=|{}.!\\"
+	markdownV2CodeSpecial = "`\\"
+)
+
+// EscapeHTML escapes provider text to be shown as is in an HTML message.
+func EscapeHTML(text string) string {
+	return htmlEscaper.Replace(text)
+}
+
+// EscapeMarkdownV2 escapes provider text to be shown as is in a MarkdownV2 message.
+func EscapeMarkdownV2(text string) string {
+	return escapeMarkdownV2(text, markdownV2Special)
+}
+
+// EscapeMarkdownV2Code escapes text to be shown as is inside a MarkdownV2 code entity.
+func EscapeMarkdownV2Code(text string) string {
+	return escapeMarkdownV2(text, markdownV2CodeSpecial)
+}
+
+func escapeMarkdownV2(text string, special string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// textFormatter writes the entities of a message in a parse mode. Plain text is written as is.
+type textFormatter struct {
+	parseMode string
+}
+
+func (s service) formatter() textFormatter {
+	return textFormatter{parseMode: s.config.ParseMode}
+}
+
+// Escape makes text safe to concatenate to a message of the parse mode.
+func (f textFormatter) Escape(text string) string {
+	switch f.parseMode {
+	case ParseModeHTML:
+		return EscapeHTML(text)
+	case ParseModeMarkdownV2:
+		return EscapeMarkdownV2(text)
+	default:
+		return text
+	}
+}
+
+func (f textFormatter) Bold(text string) string {
+	switch f.parseMode {
+	case ParseModeHTML:
+		return "<b>" + EscapeHTML(text) + "</b>"
+	case ParseModeMarkdownV2:
+		return "*" + EscapeMarkdownV2(text) + "*"
+	default:
+		return text
+	}
+}
+
+// Code renders text in monospace.
+func (f textFormatter) Code(text string) string {
+	switch f.parseMode {
+	case ParseModeHTML:
+		return "<code>" + EscapeHTML(text) + "</code>"
+	case ParseModeMarkdownV2:
+		return "`" + EscapeMarkdownV2Code(text) + "`"
+	default:
+		return text
+	}
+}
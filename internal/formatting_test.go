@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"bot/conf"
+	"bot/entity"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscapeHTML(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"CPI (YoY)", "CPI (YoY)"},
+		{"S&P <Global> PMI", "S&amp;P &lt;Global&gt; PMI"},
+		{"&amp;", "&amp;amp;"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := EscapeHTML(tt.text); got != tt.want {
+			t.Errorf("EscapeHTML(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Hi! here", "Hi\\! here"},
+		{"CPI m/m (Jan)", "CPI m/m \\(Jan\\)"},
+		{"-0.5%", "\\-0\\.5%"},
+		{"_*[]()~`>#+-=|{}.!\\", "\\_\\*\\[\\]\\(\\)\\~\\`\\>\\#\\+\\-\\=\\|\\{\\}\\.\\!\\\\"},
+		{"Non Farm Payrolls", "Non Farm Payrolls"},
+	}
+	for _, tt := range tests {
+		if got := EscapeMarkdownV2(tt.text); got != tt.want {
+			t.Errorf("EscapeMarkdownV2(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeMarkdownV2Code(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"2025-01-10 13:30 UTC", "2025-01-10 13:30 UTC"},
+		{"1.5%", "1.5%"},
+		{"a`b\\c", "a\\`b\\\\c"},
+	}
+	for _, tt := range tests {
+		if got := EscapeMarkdownV2Code(tt.text); got != tt.want {
+			t.Errorf("EscapeMarkdownV2Code(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFormattedMessagesAreValidMarkup(t *testing.T) {
+	forecast, previous, actual := 0.3, -0.1, 4.5
+	events := []entity.CalendarEvent{
+		{Date: "2025-01-10 13:30:00", Country: "US", Event: "CPI m/m (Dec) [prelim.] *core*_x", Currency: "USD", Impact: "High",
+			Forecast: &forecast, Previous: &previous, Unit: "%"},
+		{Date: "2025-01-10 12:00:00", Country: "UK", Event: "BoE Interest Rate Decision", Currency: "GBP", Impact: "High",
+			Actual: &actual, Forecast: &actual, Previous: &actual, Unit: "%"},
+		{Date: "2025-01-10 15:00:00", Country: "US", Event: "S&P <Global> PMI", Currency: "USD", Impact: "Medium"},
+	}
+	day := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	for _, parseMode := range []string{ParseModeHTML, ParseModeMarkdownV2} {
+		s := service{config: conf.Config{ParseMode: parseMode}}
+		messages := map[string]string{
+			"start":          s.PrepareStartMessageToTelegramChat(),
+			"xau":            s.PrepareXauMessage(42.5, 57.5, time.UTC),
+			"calendar":       s.PrepareEconomicCalendarForNextDayMessage(day, events),
+			"empty calendar": s.PrepareEconomicCalendarForNextDayMessage(day, nil),
+		}
+		for name, message := range messages {
+			if err := checkMarkup(message, parseMode); err != nil {
+				t.Errorf("%s message in %s: %s\n%s", name, parseMode, err, message)
+			}
+		}
+	}
+}
+
+// checkMarkup tells why text would be rejected by Telegram in parseMode, nil when valid.
+func checkMarkup(text string, parseMode string) error {
+	switch parseMode {
+	case ParseModeHTML:
+		return checkHTML(text)
+	case ParseModeMarkdownV2:
+		return checkMarkdownV2(text)
+	}
+	return nil
+}
+
+// checkMarkdownV2 accepts the entities written by textFormatter and escaped text.
+func checkMarkdownV2(text string) error {
+	var bold, code bool
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			if i+1 == len(runes) {
+				return fmt.Errorf("dangling escape at the end")
+			}
+			i++
+		case r == '`':
+			code = !code
+		case code:
+			// only ` and \ are reserved in code
+		case r == '*':
+			bold = !bold
+		case strings.ContainsRune(markdownV2Special, r):
+			return fmt.Errorf("unescaped %q at %d", r, i)
+		}
+	}
+	if bold || code {
+		return fmt.Errorf("unclosed entity")
+	}
+	return nil
+}
+
+// checkHTML accepts the tags written by textFormatter and escaped text.
+func checkHTML(text string) error {
+	var open []string
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '<':
+			end := strings.IndexByte(text[i:], '>')
+			if end < 0 {
+				return fmt.Errorf("unterminated tag at %d", i)
+			}
+			tag := text[i : i+end+1]
+			switch tag {
+			case "<b>", "<code>":
+				open = append(open, tag[1:len(tag)-1])
+			case "</b>", "</code>":
+				if len(open) == 0 || open[len(open)-1] != tag[2:len(tag)-1] {
+					return fmt.Errorf("unbalanced %s at %d", tag, i)
+				}
+				open = open[:len(open)-1]
+			default:
+				return fmt.Errorf("unsupported tag %s at %d", tag, i)
+			}
+			i += end
+		case '>':
+			return fmt.Errorf("unescaped > at %d", i)
+		case '&':
+			rest := text[i:]
+			if !strings.HasPrefix(rest, "&amp;") && !strings.HasPrefix(rest, "&lt;") && !strings.HasPrefix(rest, "&gt;") {
+				return fmt.Errorf("unescaped & at %d", i)
+			}
+		}
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("unclosed %s", open[len(open)-1])
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package internal
+
+import (
+	"bot/entity"
+	"encoding/json"
+	"github.com/enescakir/emoji"
+	"go.etcd.io/bbolt"
+	"log"
+	"strings"
+	"time"
+)
+
+// releases shown for each indicator by the /history command
+const historyReleasesShown = 6
+
+var historyBucket = []byte("events")
+
+// events already alerted by the surprise notification, with the time they were
+var alertedBucket = []byte("alerted")
+
+// separates the fields of the keys, it cannot appear in event names
+const historyKeySeparator = "\x00"
+
+// HistoryStore keeps every fetched event in an embedded database, keyed by indicator and date,
+// so released values stay available after the provider dropped them.
+type HistoryStore struct {
+	db *bbolt.DB
+}
+
+func OpenHistoryStore(path string) (*HistoryStore, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(historyBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(alertedBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &HistoryStore{db: db}, nil
+}
+
+func (h *HistoryStore) Close() error {
+	if h == nil {
+		return nil
+	}
+	return h.db.Close()
+}
+
+// indicatorKey identifies an indicator across releases, e.g. "USD" + separator + "cpi m/m".
+func indicatorKey(e entity.CalendarEvent) string {
+	name := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(e.Event)), historyKeySeparator, "")
+	return strings.ToUpper(e.Currency) + historyKeySeparator + name
+}
+
+// historyKey sorts the releases of an indicator by day. An event rescheduled within its day keeps its key.
+func historyKey(e entity.CalendarEvent) []byte {
+	day := e.Date
+	if len(day) > len("2006-01-02") {
+		day = day[:len("2006-01-02")]
+	}
+	return []byte(indicatorKey(e) + historyKeySeparator + day)
+}
+
+// Save stores the events, replacing the ones already stored for the same indicator and day.
+// Released values are never lost when a re-fetch no longer carries them.
+func (h *HistoryStore) Save(events []entity.CalendarEvent) error {
+	if h == nil || len(events) == 0 {
+		return nil
+	}
+	return h.db.Update(func(tx *bbolt.Tx) error {
+		return saveEvents(tx.Bucket(historyBucket), events)
+	})
+}
+
+func saveEvents(bucket *bbolt.Bucket, events []entity.CalendarEvent) error {
+	for _, e := range events {
+		key := historyKey(e)
+		if stored := bucket.Get(key); stored != nil {
+			var previous entity.CalendarEvent
+			if err := json.Unmarshal(stored, &previous); err == nil {
+				mergeReleasedValues(&e, previous)
+			}
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mergeReleasedValues(e *entity.CalendarEvent, previous entity.CalendarEvent) {
+	if e.Actual == nil {
+		e.Actual = previous.Actual
+	}
+	if e.Forecast == nil {
+		e.Forecast = previous.Forecast
+	}
+	if e.Previous == nil {
+		e.Previous = previous.Previous
+	}
+	if e.Change == nil {
+		e.Change = previous.Change
+	}
+	if e.Unit == "" {
+		e.Unit = previous.Unit
+	}
+}
+
+// Alerted returns the keys of the events already alerted, with the time they were.
+func (h *HistoryStore) Alerted() (map[string]time.Time, error) {
+	alerted := map[string]time.Time{}
+	if h == nil {
+		return alerted, nil
+	}
+	err := h.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(alertedBucket).ForEach(func(k, v []byte) error {
+			at, err := time.Parse(time.RFC3339, string(v))
+			if err != nil {
+				log.Printf("skipping undecodable alerted entry %q: %s", k, err.Error())
+				return nil
+			}
+			alerted[string(k)] = at
+			return nil
+		})
+	})
+	return alerted, err
+}
+
+// SaveAlerted records the events alerted at a time and forgets the ones no more tracked.
+func (h *HistoryStore) SaveAlerted(alerted map[string]time.Time, forgotten []string) error {
+	if h == nil || (len(alerted) == 0 && len(forgotten) == 0) {
+		return nil
+	}
+	return h.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(alertedBucket)
+		for key, at := range alerted {
+			if err := bucket.Put([]byte(key), []byte(at.UTC().Format(time.RFC3339))); err != nil {
+				return err
+			}
+		}
+		for _, key := range forgotten {
+			if err := bucket.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Releases returns the stored events of the indicators whose name contains query, optionally preceded by
+// a currency (e.g. "USD CPI"), sorted by indicator and date.
+func (h *HistoryStore) Releases(query string) ([]entity.CalendarEvent, error) {
+	if h == nil {
+		return nil, nil
+	}
+
+	currency, name := "", strings.ToLower(strings.TrimSpace(query))
+	if fields := strings.Fields(query); len(fields) > 1 && countryForCurrency(fields[0]) != "" {
+		currency = strings.ToUpper(fields[0])
+		name = strings.ToLower(strings.Join(fields[1:], " "))
+	}
+
+	var events []entity.CalendarEvent
+	err := h.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(historyBucket).ForEach(func(k, v []byte) error {
+			var e entity.CalendarEvent
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if currency != "" && !strings.EqualFold(e.Currency, currency) {
+				return nil
+			}
+			if !strings.Contains(strings.ToLower(e.Event), name) {
+				return nil
+			}
+			events = append(events, e)
+			return nil
+		})
+	})
+	return events, err
+}
+
+// PrepareHistoryMessage lists the last released values of the indicators matching query.
+func (s service) PrepareHistoryMessage(query string, loc *time.Location) string {
+	if s.history == nil {
+		return emoji.CrossMark.String() + " The events history is not enabled"
+	}
+	events, err := s.history.Releases(query)
+	if err != nil {
+		log.Printf("could not read events history %s", err.Error())
+		return emoji.CrossMark.String() + " Could not read the events history, try again later"
+	}
+
+	// events are sorted by indicator then date: keep the released ones grouped by indicator
+	var indicators []string
+	releases := map[string][]entity.CalendarEvent{}
+	for _, e := range events {
+		if e.Actual == nil {
+			continue
+		}
+		key := indicatorKey(e)
+		if _, ok := releases[key]; !ok {
+			indicators = append(indicators, key)
+		}
+		releases[key] = append(releases[key], e)
+	}
+	if len(indicators) == 0 {
+		return emoji.CrossMark.String() + " No released values for " + query
+	}
+
+	message := ""
+	for _, key := range indicators {
+		indicatorReleases := releases[key]
+		latest := indicatorReleases[len(indicatorReleases)-1]
+		message = message + emoji.Scroll.String() + " " + latest.Event + " (" + latest.Currency + ")  " + GetEmojiCountry(latest.Country) + "\n"
+
+		if len(indicatorReleases) > historyReleasesShown {
+			indicatorReleases = indicatorReleases[len(indicatorReleases)-historyReleasesShown:]
+		}
+		for i := len(indicatorReleases) - 1; i >= 0; i-- {
+			e := indicatorReleases[i]
+			message = message + "  " + formatEventDate(e, loc) + ": " + FormatEventValue(e.Actual, e.Unit) +
+				" (forecast " + FormatEventValue(e.Forecast, e.Unit) + ", previous " + FormatEventValue(e.Previous, e.Unit) + ")\n"
+		}
+		message = message + "\n"
+	}
+	return message
+}
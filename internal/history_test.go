@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"bot/entity"
+	"path/filepath"
+	"testing"
+)
+
+func openTestHistoryStore(t *testing.T, path string) *HistoryStore {
+	t.Helper()
+	history, err := OpenHistoryStore(path)
+	if err != nil {
+		t.Fatalf("OpenHistoryStore: %v", err)
+	}
+	t.Cleanup(func() { _ = history.Close() })
+	return history
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestHistoryRescheduledWithinDay(t *testing.T) {
+	history := openTestHistoryStore(t, filepath.Join(t.TempDir(), "history.db"))
+
+	released := entity.CalendarEvent{Date: "2025-03-05 13:30:00", Country: "US", Event: "CPI m/m", Currency: "USD",
+		Actual: float(0.4), Forecast: float(0.3)}
+	moved := released
+	moved.Date = "2025-03-05 15:00:00"
+	moved.Actual = nil
+	if err := history.Save([]entity.CalendarEvent{released}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := history.Save([]entity.CalendarEvent{moved}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	events, err := history.Releases("USD CPI")
+	if err != nil {
+		t.Fatalf("Releases: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d releases, want 1: %v", len(events), events)
+	}
+	if events[0].Date != moved.Date || events[0].Actual == nil || *events[0].Actual != 0.4 {
+		t.Errorf("release = %+v, want the moved date with the released actual", events[0])
+	}
+}
+
+func TestHistoryReleasesMatching(t *testing.T) {
+	history := openTestHistoryStore(t, filepath.Join(t.TempDir(), "history.db"))
+
+	err := history.Save([]entity.CalendarEvent{
+		{Date: "2025-02-12 13:30:00", Country: "US", Event: "CPI m/m", Currency: "USD", Actual: float(0.5)},
+		{Date: "2025-03-12 13:30:00", Country: "US", Event: "CPI m/m", Currency: "USD", Actual: float(0.2)},
+		{Date: "2025-03-19 10:00:00", Country: "EU", Event: "CPI y/y | Final", Currency: "EUR", Actual: float(2.3)},
+		{Date: "2025-03-20 07:00:00", Country: "UK", Event: "GDP m/m", Currency: "GBP", Actual: float(0.1)},
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"cpi", []string{"EUR CPI y/y | Final 2025-03-19", "USD CPI m/m 2025-02-12", "USD CPI m/m 2025-03-12"}},
+		{"USD cpi", []string{"USD CPI m/m 2025-02-12", "USD CPI m/m 2025-03-12"}},
+		{"eur | final", []string{"EUR CPI y/y | Final 2025-03-19"}},
+		{"y/y |", []string{"EUR CPI y/y | Final 2025-03-19"}},
+		{"GBP CPI", nil},
+		{"gdp", []string{"GBP GDP m/m 2025-03-20"}},
+	}
+	for _, tt := range tests {
+		events, err := history.Releases(tt.query)
+		if err != nil {
+			t.Fatalf("Releases(%q): %v", tt.query, err)
+		}
+		var got []string
+		for _, e := range events {
+			got = append(got, e.Currency+" "+e.Event+" "+e.Date[:10])
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Releases(%q) = %q, want %q", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Releases(%q) = %q, want %q", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
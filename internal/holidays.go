@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"bot/entity"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// markets announced in the daily digest, in this order
+var holidayMarkets = []string{"US", "UK", "EU", "JP"}
+
+// HolidayCalendar tells which days the markets are closed, by market code (US, UK, EU, JP).
+type HolidayCalendar struct {
+	holidays map[string]map[string]entity.Holiday
+}
+
+func NewHolidayCalendar(holidays map[string][]entity.Holiday) HolidayCalendar {
+	calendar := HolidayCalendar{holidays: map[string]map[string]entity.Holiday{}}
+	for market, days := range holidays {
+		calendar.holidays[market] = map[string]entity.Holiday{}
+		for _, holiday := range days {
+			calendar.holidays[market][holiday.Date] = holiday
+		}
+	}
+	return calendar
+}
+
+// MissingYear returns the markets announced in the daily digest without any holiday in year,
+// whose closures would go unnoticed.
+func (c HolidayCalendar) MissingYear(year int) []string {
+	prefix := strconv.Itoa(year) + "-"
+	var missing []string
+	for _, market := range holidayMarkets {
+		covered := false
+		for date := range c.holidays[market] {
+			if strings.HasPrefix(date, prefix) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			missing = append(missing, market)
+		}
+	}
+	return missing
+}
+
+// Holiday returns the holiday of a market on the date of day, if any.
+func (c HolidayCalendar) Holiday(market string, day time.Time) (entity.Holiday, bool) {
+	holiday, ok := c.holidays[market][day.Format("2006-01-02")]
+	return holiday, ok
+}
+
+// IsTradingDay tells whether a market is open on the date of day: not on weekends nor holidays.
+func (c HolidayCalendar) IsTradingDay(market string, day time.Time) bool {
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+	_, holiday := c.Holiday(market, day)
+	return !holiday
+}
+
+// PreviousSession returns the last trading day of a market before the date of day.
+func (c HolidayCalendar) PreviousSession(market string, day time.Time) time.Time {
+	session := day.AddDate(0, 0, -1)
+	for !c.IsTradingDay(market, session) {
+		session = session.AddDate(0, 0, -1)
+	}
+	return session
+}
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"bot/conf"
+	"bot/entity"
+	"reflect"
+	"testing"
+)
+
+func TestHolidayCalendarMissingYear(t *testing.T) {
+	bundled, err := conf.LoadHolidays("../holidays.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial := map[string][]entity.Holiday{
+		"US": {{Date: "2027-12-24", Name: "Christmas Day (observed)"}},
+		"UK": {{Date: "2026-12-25", Name: "Christmas Day"}},
+		"JP": {{Date: "2027-01-01", Name: "New Year's Day"}},
+	}
+
+	tests := []struct {
+		name     string
+		holidays map[string][]entity.Holiday
+		year     int
+		want     []string
+	}{
+		{"bundled", bundled, 2025, nil},
+		{"bundled last year", bundled, 2027, nil},
+		{"bundled after the last year", bundled, 2028, []string{"US", "UK", "EU", "JP"}},
+		{"partial", partial, 2027, []string{"UK", "EU"}},
+		{"empty", nil, 2027, []string{"US", "UK", "EU", "JP"}},
+	}
+	for _, tt := range tests {
+		if got := NewHolidayCalendar(tt.holidays).MissingYear(tt.year); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: MissingYear(%d) = %v, want %v", tt.name, tt.year, got, tt.want)
+		}
+	}
+}
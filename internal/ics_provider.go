@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"bot/entity"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// icsCalendarProvider reads VEVENTs from an iCalendar (.ics) file or URL.
+// Currency and impact are taken from CATEGORIES or from X- properties ending in CURRENCY, IMPACT and COUNTRY
+// (e.g. X-CURRENCY, X-FF-IMPACT). Released values are read from X- properties ending in ACTUAL, FORECAST,
+// PREVIOUS and UNIT.
+type icsCalendarProvider struct {
+	source string
+	// location of floating times and of unknown TZIDs
+	location *time.Location
+}
+
+// icsProperty is a content line such as DTSTART;TZID=Europe/London:20240701T083000
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func (p icsCalendarProvider) GetEvents(from time.Time, to time.Time) ([]entity.CalendarEvent, error) {
+	body, err := openCalendarSource(p.source)
+	if err != nil {
+		log.Printf("error while opening iCalendar source %s", err.Error())
+		return nil, err
+	}
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			log.Printf("error when closing iCalendar source: %s", err.Error())
+		}
+	}(body)
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return eventsBetween(parseIcs(string(content), p.location), from, to), nil
+}
+
+func parseIcs(content string, location *time.Location) []entity.CalendarEvent {
+	// unfold long lines, continuation lines start with a space or a tab
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\n ", "")
+	content = strings.ReplaceAll(content, "\n\t", "")
+
+	var events []entity.CalendarEvent
+	var properties []icsProperty
+	inEvent := false
+
+	for _, line := range strings.Split(content, "\n") {
+		if line == "" {
+			continue
+		}
+		property := parseIcsProperty(line)
+		switch {
+		case property.name == "BEGIN" && strings.EqualFold(property.value, "VEVENT"):
+			inEvent = true
+			properties = nil
+		case property.name == "END" && strings.EqualFold(property.value, "VEVENT"):
+			inEvent = false
+			event, ok := newIcsEvent(properties, location)
+			if ok {
+				events = append(events, event)
+			}
+		case inEvent:
+			properties = append(properties, property)
+		}
+	}
+	return events
+}
+
+func parseIcsProperty(line string) icsProperty {
+	// the value starts at the first colon not enclosed in a quoted parameter
+	quoted := false
+	separator := len(line)
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			separator = i
+			break
+		}
+	}
+
+	property := icsProperty{params: map[string]string{}}
+	if separator < len(line) {
+		property.value = line[separator+1:]
+	}
+
+	parts := strings.Split(line[:separator], ";")
+	property.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		property.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return property
+}
+
+func newIcsEvent(properties []icsProperty, location *time.Location) (entity.CalendarEvent, bool) {
+	var event entity.CalendarEvent
+	var start time.Time
+
+	for _, property := range properties {
+		switch {
+		case property.name == "DTSTART":
+			t, err := parseIcsTime(property, location)
+			if err != nil {
+				log.Printf("Error parsing iCalendar date %s: %v", property.value, err)
+				return event, false
+			}
+			start = t
+		case property.name == "SUMMARY":
+			event.Event = unescapeIcsText(property.value)
+		case property.name == "CATEGORIES":
+			for _, category := range strings.Split(unescapeIcsText(property.value), ",") {
+				category = strings.TrimSpace(category)
+				if countryForCurrency(category) != "" {
+					event.Currency = strings.ToUpper(category)
+				} else if impact := normalizeImpact(category); impact == "Low" || impact == "Medium" || impact == "High" {
+					event.Impact = impact
+				}
+			}
+		case strings.HasPrefix(property.name, "X-") && strings.HasSuffix(property.name, "CURRENCY"):
+			event.Currency = strings.ToUpper(strings.TrimSpace(unescapeIcsText(property.value)))
+		case strings.HasPrefix(property.name, "X-") && strings.HasSuffix(property.name, "IMPACT"):
+			event.Impact = normalizeImpact(unescapeIcsText(property.value))
+		case strings.HasPrefix(property.name, "X-") && strings.HasSuffix(property.name, "COUNTRY"):
+			event.Country = strings.ToUpper(strings.TrimSpace(unescapeIcsText(property.value)))
+		case strings.HasPrefix(property.name, "X-") && strings.HasSuffix(property.name, "UNIT"):
+			event.Unit = strings.TrimSpace(unescapeIcsText(property.value))
+		case strings.HasPrefix(property.name, "X-") && strings.HasSuffix(property.name, "ACTUAL"):
+			setEventValue(&event, &event.Actual, unescapeIcsText(property.value))
+		case strings.HasPrefix(property.name, "X-") && strings.HasSuffix(property.name, "FORECAST"):
+			setEventValue(&event, &event.Forecast, unescapeIcsText(property.value))
+		case strings.HasPrefix(property.name, "X-") && strings.HasSuffix(property.name, "PREVIOUS"):
+			setEventValue(&event, &event.Previous, unescapeIcsText(property.value))
+		}
+	}
+	completeEventChange(&event)
+
+	if start.IsZero() || event.Event == "" {
+		return event, false
+	}
+	if event.Country == "" {
+		event.Country = countryForCurrency(event.Currency)
+	}
+	event.Date = start.UTC().Format(entity.CalendarEventDateLayout)
+	return event, true
+}
+
+// parseIcsTime supports UTC times (Z suffix), times with a TZID parameter, floating times and all-day dates.
+func parseIcsTime(property icsProperty, location *time.Location) (time.Time, error) {
+	value := strings.TrimSpace(property.value)
+
+	if property.params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		return time.ParseInLocation("20060102", value, location)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+
+	loc := location
+	if tzid := property.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = l
+		} else {
+			log.Printf("unknown TZID %s, using %s", tzid, location)
+		}
+	}
+	return time.ParseInLocation("20060102T150405", value, loc)
+}
+
+func unescapeIcsText(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseIcsFixture(t *testing.T) {
+	content, err := os.ReadFile("testdata/economic_calendar.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rome, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Skipf("no timezone database: %v", err)
+	}
+
+	tests := []struct {
+		title    string
+		utcDate  string
+		romeDate string
+		country  string
+		currency string
+		impact   string
+		actual   string
+		forecast string
+		previous string
+		unit     string
+	}{
+		// TZID parameter, impact from CATEGORIES
+		{"Core CPI m/m", "2025-03-12 12:30:00", "2025-03-12 12:30:00", "US", "USD", "High", "", "0.3", "0.4", "%"},
+		// UTC time, impact from an X- property
+		{"GDP m/m", "2025-03-14 07:00:00", "2025-03-14 07:00:00", "UK", "GBP", "High", "-0.1", "0.1", "0.4", "%"},
+		// floating time, in the location of the provider
+		{"ECB President Lagarde Speaks", "2025-03-13 10:00:00", "2025-03-13 09:00:00", "EU", "EUR", "Medium", "", "", "", ""},
+		// all-day date, at the start of the day in the location of the provider
+		{"Bank Holiday", "2025-03-20 00:00:00", "2025-03-19 23:00:00", "JP", "JPY", "", "", "", "", ""},
+		// folded and escaped summary, quoted TZID
+		{"Federal Budget Balance, Treasury Statement for February and Fiscal Year to Date", "2025-03-12 18:00:00", "2025-03-12 18:00:00",
+			"US", "USD", "Low", "", "-308.5", "-129", "B"},
+		// unknown TZID, in the location of the provider
+		{"RBA Assist Gov Hunter Speaks", "2025-03-11 11:30:00", "2025-03-11 10:30:00", "AU", "AUD", "Medium", "", "", "", ""},
+	}
+
+	for _, location := range []*time.Location{time.UTC, rome} {
+		events := parseIcs(string(content), location)
+		// the event without summary is skipped
+		if len(events) != len(tests) {
+			t.Fatalf("in %s got %d events, want %d", location, len(events), len(tests))
+		}
+		for _, tt := range tests {
+			e := findEvent(t, events, tt.title)
+			want := tt.utcDate
+			if location == rome {
+				want = tt.romeDate
+			}
+			if e.Date != want {
+				t.Errorf("%s in %s: date = %s, want %s", tt.title, location, e.Date, want)
+			}
+			if e.Country != tt.country || e.Currency != tt.currency || e.Impact != tt.impact {
+				t.Errorf("%s: country, currency, impact = %s, %s, %s, want %s, %s, %s",
+					tt.title, e.Country, e.Currency, e.Impact, tt.country, tt.currency, tt.impact)
+			}
+			if formatValue(e.Actual) != orNa(tt.actual) || formatValue(e.Forecast) != orNa(tt.forecast) ||
+				formatValue(e.Previous) != orNa(tt.previous) || e.Unit != tt.unit {
+				t.Errorf("%s: actual, forecast, previous, unit = %s, %s, %s, %s, want %s, %s, %s, %s", tt.title,
+					formatValue(e.Actual), formatValue(e.Forecast), formatValue(e.Previous), e.Unit,
+					orNa(tt.actual), orNa(tt.forecast), orNa(tt.previous), tt.unit)
+			}
+		}
+	}
+}
+
+// orNa is the rendering of a value not known.
+func orNa(value string) string {
+	if value == "" {
+		return formatValue(nil)
+	}
+	return value
+}
+
+func TestParseIcsTime(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"DTSTART:20250314T070000Z", "2025-03-14T07:00:00Z"},
+		{"DTSTART;TZID=America/New_York:20250312T083000", "2025-03-12T12:30:00Z"},
+		{"DTSTART;TZID=America/New_York:20250305T083000", "2025-03-05T13:30:00Z"},
+		{"DTSTART;TZID=\"Europe/London\":20250312T070000", "2025-03-12T07:00:00Z"},
+		{"DTSTART;TZID=/Europe/London:20250712T070000", "2025-07-12T06:00:00Z"},
+		// floating and unknown TZID in the default location, UTC+9
+		{"DTSTART:20250313T100000", "2025-03-13T01:00:00Z"},
+		{"DTSTART;TZID=Nowhere/Unknown:20250313T100000", "2025-03-13T01:00:00Z"},
+		{"DTSTART;VALUE=DATE:20250320", "2025-03-19T15:00:00Z"},
+		{"DTSTART:20250320", "2025-03-19T15:00:00Z"},
+	}
+	location := time.FixedZone("JST", 9*60*60)
+	for _, tt := range tests {
+		got, err := parseIcsTime(parseIcsProperty(tt.line), location)
+		if err != nil {
+			t.Errorf("parseIcsTime(%s): %v", tt.line, err)
+			continue
+		}
+		if got.UTC().Format(time.RFC3339) != tt.want {
+			t.Errorf("parseIcsTime(%s) = %s, want %s", tt.line, got.UTC().Format(time.RFC3339), tt.want)
+		}
+	}
+}
+
+func TestParseIcsFoldedLines(t *testing.T) {
+	content := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:2025031\r\n 4T070000Z\r\nSUMMARY:Unemployment \r\n\tClaimant Count Change\r\n" +
+		"X-CUR\n RENCY:GBP\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	events := parseIcs(content, time.UTC)
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Event != "Unemployment Claimant Count Change" || e.Date != "2025-03-14 07:00:00" || e.Currency != "GBP" {
+		t.Errorf("event = %+v", e)
+	}
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"bot/entity"
+	"bot/entity/telegram"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the most results accepted by answerInlineQuery
+const inlineQueryMaxResults = 50
+
+// seconds Telegram may cache the results of an inline query
+const inlineQueryCacheTime = 300
+
+func (s service) AnswerInlineQuery(inlineQueryId string, results []telegram.InlineQueryResultArticle) (string, error) {
+	if results == nil {
+		results = []telegram.InlineQueryResultArticle{}
+	}
+	encoded, err := json.Marshal(results)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Answering inline query %s with %d results", inlineQueryId, len(results))
+	return s.postToTelegram("/answerInlineQuery",
+		url.Values{
+			"inline_query_id": {inlineQueryId},
+			"results":         {string(encoded)},
+			"cache_time":      {strconv.Itoa(inlineQueryCacheTime)},
+		})
+}
+
+// PrepareInlineQueryResults searches the upcoming cached events whose name, currency or country match
+// every word of the query. Times are rendered in the timezone of the private chat of the user, if subscribed.
+func (s service) PrepareInlineQueryResults(query telegram.InlineQuery) []telegram.InlineQueryResultArticle {
+	loc := time.UTC
+	if recipient, ok := s.recipients.Get(query.From.Id, 0); ok {
+		loc = loadLocation(recipient.Timezone)
+	}
+
+	terms := strings.Fields(strings.ToLower(query.Query))
+
+	var results []telegram.InlineQueryResultArticle
+	for _, e := range s.cache.upcoming(time.Now()) {
+		if !matchesInlineQuery(e, terms) {
+			continue
+		}
+		results = append(results, telegram.InlineQueryResultArticle{
+			Type:        "article",
+			Id:          inlineResultId(e),
+			Title:       GetEmojiCountry(e.Country) + " " + e.Event,
+			Description: formatEventDate(e, loc) + "  " + e.Currency + "  " + e.Impact + " " + GetEmojiSemaphore(e.Impact),
+			InputMessageContent: telegram.InputTextMessageContent{
+				MessageText: s.PrepareEventMessage(e, loc),
+				ParseMode:   s.config.ParseMode,
+			},
+		})
+		if len(results) == inlineQueryMaxResults {
+			break
+		}
+	}
+	return results
+}
+
+// matchesInlineQuery tells whether every term is found in the name, the currency or the country of the event.
+// An empty query matches every event.
+func matchesInlineQuery(e entity.CalendarEvent, terms []string) bool {
+	text := strings.ToLower(e.Event + " " + e.Currency + " " + e.Country)
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// inlineResultId derives a stable id, at most 64 bytes as required by Telegram, from the event identity.
+func inlineResultId(e entity.CalendarEvent) string {
+	h := fnv.New64a()
+	h.Write([]byte(e.Key()))
+	return fmt.Sprintf("%x", h.Sum64())
+}
@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// the most UTF-16 code units Telegram accepts in a message
+const telegramMaxMessageLength = 4096
+
+// room kept in every part for its "(n/m)" header
+const messagePartHeaderLength = 16
+
+// separators tried in order to split a long message: the blank line between events, then the line end
+var messageSeparators = []string{"\n\n", "\n"}
+
+// splitMessage cuts a text too long for Telegram into numbered parts, on event boundaries when possible.
+// Escapes of parseMode are never cut, and an entity cut in a single line longer than a part is closed
+// and reopened, so every part is valid on its own.
+func splitMessage(text string, parseMode string) []string {
+	if utf16Length(text) <= telegramMaxMessageLength {
+		return []string{text}
+	}
+
+	var parts []string
+	for _, chunk := range splitOn(text, telegramMaxMessageLength-messagePartHeaderLength, messageSeparators, parseMode) {
+		if strings.TrimSpace(chunk) != "" {
+			parts = append(parts, chunk)
+		}
+	}
+
+	f := textFormatter{parseMode: parseMode}
+	for i := range parts {
+		parts[i] = f.Escape("("+strconv.Itoa(i+1)+"/"+strconv.Itoa(len(parts))+")") + "\n" + parts[i]
+	}
+	return parts
+}
+
+// splitOn cuts text in chunks of at most limit UTF-16 code units, joining as many pieces between the first
+// separator as fit in a chunk. Pieces still too long are cut on the next separators.
+func splitOn(text string, limit int, separators []string, parseMode string) []string {
+	if utf16Length(text) <= limit {
+		return []string{text}
+	}
+	if len(separators) == 0 {
+		return cutLine(text, limit, parseMode)
+	}
+
+	separator := separators[0]
+	var chunks []string
+	var current string
+	started := false
+	for _, piece := range splitOutsideEntities(text, separator, parseMode) {
+		if started && utf16Length(current+separator+piece) <= limit {
+			current = current + separator + piece
+			continue
+		}
+		if started {
+			chunks = append(chunks, current)
+		}
+		pieceChunks := splitOn(piece, limit, separators[1:], parseMode)
+		chunks = append(chunks, pieceChunks[:len(pieceChunks)-1]...)
+		current = pieceChunks[len(pieceChunks)-1]
+		started = true
+	}
+	return append(chunks, current)
+}
+
+// splitOutsideEntities splits text around the separators found outside the entities of parseMode.
+func splitOutsideEntities(text string, separator string, parseMode string) []string {
+	var pieces []string
+	var current strings.Builder
+	entities := openEntities{parseMode: parseMode}
+	for i, piece := range strings.Split(text, separator) {
+		if i > 0 {
+			if len(entities.open) == 0 {
+				pieces = append(pieces, current.String())
+				current.Reset()
+			} else {
+				current.WriteString(separator)
+			}
+		}
+		current.WriteString(piece)
+		for _, token := range markupTokens(piece, parseMode) {
+			entities = entities.with(token)
+		}
+	}
+	return append(pieces, current.String())
+}
+
+// cutLine cuts text in chunks of at most limit UTF-16 code units, never inside a MarkdownV2 escape, an HTML tag
+// or character reference. The entities open at a cut are closed at the end of the chunk and reopened in the next one.
+func cutLine(text string, limit int, parseMode string) []string {
+	var chunks []string
+	entities := openEntities{parseMode: parseMode}
+	var current strings.Builder
+	length := 0
+	empty := true
+	for _, token := range markupTokens(text, parseMode) {
+		next := entities.with(token)
+		if !empty && length+utf16Length(token+next.closers()) > limit {
+			chunks = append(chunks, current.String()+entities.closers())
+			current.Reset()
+			current.WriteString(entities.openers())
+			length = utf16Length(entities.openers())
+		}
+		current.WriteString(token)
+		length += utf16Length(token)
+		entities = next
+		empty = false
+	}
+	return append(chunks, current.String())
+}
+
+// markupTokens splits text into the pieces a cut cannot go through: a MarkdownV2 escape,
+// an HTML tag or character reference, or a single character.
+func markupTokens(text string, parseMode string) []string {
+	var tokens []string
+	for len(text) > 0 {
+		n := markupTokenLength(text, parseMode)
+		tokens = append(tokens, text[:n])
+		text = text[n:]
+	}
+	return tokens
+}
+
+func markupTokenLength(text string, parseMode string) int {
+	_, size := utf8.DecodeRuneInString(text)
+	switch parseMode {
+	case ParseModeMarkdownV2:
+		if text[0] == '\\' && len(text) > 1 {
+			_, escaped := utf8.DecodeRuneInString(text[1:])
+			return 1 + escaped
+		}
+	case ParseModeHTML:
+		if text[0] == '<' {
+			if end := strings.IndexByte(text, '>'); end >= 0 {
+				return end + 1
+			}
+		}
+		if text[0] == '&' {
+			if end := strings.IndexByte(text, ';'); end >= 0 && !strings.ContainsAny(text[1:end], " &<") {
+				return end + 1
+			}
+		}
+	}
+	return size
+}
+
+// openEntities are the entities written by textFormatter, bold and code, open at a point of a message.
+type openEntities struct {
+	parseMode string
+	// opening markers or tags, innermost last
+	open []string
+}
+
+// with returns the entities open after token.
+func (e openEntities) with(token string) openEntities {
+	open := append([]string(nil), e.open...)
+	switch e.parseMode {
+	case ParseModeMarkdownV2:
+		inCode := len(open) > 0 && open[len(open)-1] == "`"
+		if token == "`" || (token == "*" && !inCode) {
+			if len(open) > 0 && open[len(open)-1] == token {
+				open = open[:len(open)-1]
+			} else {
+				open = append(open, token)
+			}
+		}
+	case ParseModeHTML:
+		if strings.HasPrefix(token, "</") {
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		} else if strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">") {
+			open = append(open, token)
+		}
+	}
+	return openEntities{parseMode: e.parseMode, open: open}
+}
+
+// openers reopens the entities, outermost first.
+func (e openEntities) openers() string {
+	return strings.Join(e.open, "")
+}
+
+// closers closes the entities, innermost first.
+func (e openEntities) closers() string {
+	var b strings.Builder
+	for i := len(e.open) - 1; i >= 0; i-- {
+		if e.parseMode == ParseModeHTML {
+			name, _, _ := strings.Cut(strings.Trim(e.open[i], "<>"), " ")
+			b.WriteString("</" + name + ">")
+		} else {
+			b.WriteString(e.open[i])
+		}
+	}
+	return b.String()
+}
+
+// utf16Length counts the UTF-16 code units of text, the unit of the Telegram length limits.
+func utf16Length(text string) int {
+	length := 0
+	for _, r := range text {
+		length += utf16RuneLength(r)
+	}
+	return length
+}
+
+func utf16RuneLength(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
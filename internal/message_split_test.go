@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"bot/conf"
+	"bot/entity"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUtf16Length(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"CPI m/m", 7},
+		{"€ à", 3},
+		{"📅", 2},
+		{"🇺🇸 USD", 8},
+		{"👍🏽", 4},
+	}
+	for _, tt := range tests {
+		if got := utf16Length(tt.text); got != tt.want {
+			t.Errorf("utf16Length(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSplitMessageShort(t *testing.T) {
+	text := "📅 " + strings.Repeat("a", telegramMaxMessageLength-3)
+	if parts := splitMessage(text, ""); len(parts) != 1 || parts[0] != text {
+		t.Errorf("got %d parts, want the text unchanged", len(parts))
+	}
+}
+
+func TestSplitOnSeparators(t *testing.T) {
+	text := "aaaa\n\nbbbb\n\ncccc\ndddd"
+	got := splitOn(text, 10, messageSeparators, "")
+	want := []string{"aaaa\n\nbbbb", "cccc\ndddd"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitOn = %q, want %q", got, want)
+	}
+
+	got = splitOn("aaaa\nbbbb\ncccc", 9, messageSeparators, "")
+	want = []string{"aaaa\nbbbb", "cccc"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitOn = %q, want %q", got, want)
+	}
+}
+
+func TestSplitMessageCalendar(t *testing.T) {
+	forecast, previous := 0.3, -0.1
+	var events []entity.CalendarEvent
+	for i := 0; i < 60; i++ {
+		events = append(events, entity.CalendarEvent{
+			Date:     fmt.Sprintf("2025-03-12 %02d:%02d:00", i/4, i%4*15),
+			Country:  "US",
+			Event:    fmt.Sprintf("S&P <Global> CPI m/m (Feb) #%d!", i),
+			Currency: "USD",
+			Impact:   "High",
+			Forecast: &forecast,
+			Previous: &previous,
+			Unit:     "%",
+		})
+	}
+
+	for _, parseMode := range []string{"", ParseModeHTML, ParseModeMarkdownV2} {
+		t.Run("mode "+parseMode, func(t *testing.T) {
+			s := service{config: conf.Config{ParseMode: parseMode}}
+			text := s.PrepareEconomicCalendarForNextDayMessage(time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC), events)
+			if utf16Length(text) <= telegramMaxMessageLength {
+				t.Fatalf("calendar of %d units is not long enough", utf16Length(text))
+			}
+
+			parts := splitMessage(text, parseMode)
+			if len(parts) < 2 {
+				t.Fatalf("got %d parts, want several", len(parts))
+			}
+			f := textFormatter{parseMode: parseMode}
+			found := 0
+			for i, part := range parts {
+				header := f.Escape(fmt.Sprintf("(%d/%d)", i+1, len(parts))) + "\n"
+				if !strings.HasPrefix(part, header) {
+					t.Errorf("part %d starts with %q, want %q", i+1, part[:10], header)
+				}
+				if utf16Length(part) > telegramMaxMessageLength {
+					t.Errorf("part %d has %d units", i+1, utf16Length(part))
+				}
+				if err := checkMarkup(part, parseMode); err != nil {
+					t.Errorf("part %d: %s", i+1, err)
+				}
+				// parts are cut between events
+				if strings.Count(part, "DATE:") != strings.Count(part, "PREVIOUS:") {
+					t.Errorf("part %d cuts an event", i+1)
+				}
+				found += strings.Count(part, "DATE:")
+			}
+			if found != len(events) {
+				t.Errorf("got %d events in the parts, want %d", found, len(events))
+			}
+		})
+	}
+}
+
+func TestCutLineKeepsEntities(t *testing.T) {
+	tests := []struct {
+		parseMode string
+		text      string
+	}{
+		{ParseModeMarkdownV2, strings.Repeat("*S&P 500 \\(Feb\\)* `1.5% \\` 📅` plain\\! ", 200)},
+		{ParseModeMarkdownV2, "*" + strings.Repeat("bold\\. 📅 ", 700) + "*"},
+		{ParseModeMarkdownV2, "`" + strings.Repeat("code * 1.5% ", 700) + "`"},
+		{ParseModeHTML, strings.Repeat("<b>S&amp;P &lt;500&gt;</b> <code>1.5% 📅</code> plain ", 150)},
+		{ParseModeHTML, "<b>" + strings.Repeat("bold &amp; 📅 ", 700) + "</b>"},
+	}
+	for _, tt := range tests {
+		chunks := cutLine(tt.text, 1000, tt.parseMode)
+		if len(chunks) < 2 {
+			t.Errorf("%s: got %d chunks, want several", tt.parseMode, len(chunks))
+		}
+		for i, chunk := range chunks {
+			if utf16Length(chunk) > 1000 {
+				t.Errorf("%s: chunk %d has %d units", tt.parseMode, i, utf16Length(chunk))
+			}
+			if err := checkMarkup(chunk, tt.parseMode); err != nil {
+				t.Errorf("%s: chunk %d: %s\n%s", tt.parseMode, i, err, chunk)
+			}
+		}
+	}
+}
+
+func TestSplitMessageLongLine(t *testing.T) {
+	text := "*" + strings.Repeat("📅 Non\\-Farm \\(Feb\\) ", 400) + "*"
+	parts := splitMessage(text, ParseModeMarkdownV2)
+	if len(parts) < 2 {
+		t.Fatalf("got %d parts, want several", len(parts))
+	}
+	for i, part := range parts {
+		if utf16Length(part) > telegramMaxMessageLength {
+			t.Errorf("part %d has %d units", i+1, utf16Length(part))
+		}
+		if err := checkMarkup(part, ParseModeMarkdownV2); err != nil {
+			t.Errorf("part %d: %s", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"bot/entity/telegram"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ways of receiving the updates, selected by the updates_mode config
+const (
+	UpdatesModeWebhook = "webhook"
+	UpdatesModePolling = "polling"
+)
+
+// seconds getUpdates waits for an update when no timeout is configured
+const defaultPollingTimeoutSeconds = 30
+
+// delays between failed getUpdates calls, doubled at every failure
+const (
+	pollingMinBackoff = time.Second
+	pollingMaxBackoff = time.Minute
+)
+
+// GetUpdates long polls the updates following offset, waiting at most timeout seconds for one.
+func (s service) GetUpdates(ctx context.Context, offset int, timeout int) ([]telegram.Update, error) {
+	encodedAllowedUpdates, err := json.Marshal(allowedUpdates)
+	if err != nil {
+		return nil, err
+	}
+	var updates []telegram.Update
+	err = s.callTelegram(ctx, "/getUpdates",
+		url.Values{
+			"offset":          {strconv.Itoa(offset)},
+			"timeout":         {strconv.Itoa(timeout)},
+			"allowed_updates": {string(encodedAllowedUpdates)},
+		}, &updates)
+	return updates, err
+}
+
+// callTelegram calls a Bot API method, e.g. "/getUpdates", decoding its result into result.
+// A response not ok is returned as an error.
+func (s service) callTelegram(ctx context.Context, method string, values url.Values, result interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		s.config.TelegramApiBaseUrl+s.config.TelegramBotToken+method, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Printf("error when closing telegram answer: %s", err.Error())
+		}
+	}(response.Body)
+
+	var telegramResponse telegram.Response
+	if err := json.NewDecoder(response.Body).Decode(&telegramResponse); err != nil {
+		return fmt.Errorf("could not decode %s answer: %w", method, err)
+	}
+	if !telegramResponse.Ok {
+		return fmt.Errorf("%s failed with code %d: %s", method, telegramResponse.ErrorCode, telegramResponse.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(telegramResponse.Result, result)
+}
+
+// PollUpdates dispatches the updates received with getUpdates, like the webhook does, until ctx is done.
+// Failed calls are retried with an increasing delay.
+func PollUpdates(ctx context.Context, service Service, commands *CommandRegistry, timeoutSeconds int) {
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultPollingTimeoutSeconds
+	}
+
+	offset := 0
+	backoff := pollingMinBackoff
+	for {
+		updates, err := service.GetUpdates(ctx, offset, timeoutSeconds)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			log.Printf("could not get updates %s, retrying in %s", err.Error(), backoff)
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, pollingMaxBackoff)
+			continue
+		}
+		backoff = pollingMinBackoff
+
+		for _, update := range updates {
+			offset = update.UpdateId + 1
+			dispatchUpdate(service, commands, update)
+		}
+	}
+
+	// confirm the dispatched updates, so they are not received again after a restart
+	if offset > 0 {
+		if _, err := service.GetUpdates(context.Background(), offset, 0); err != nil {
+			log.Printf("could not confirm updates %s", err.Error())
+		}
+	}
+	log.Printf("stopped polling updates")
+}
@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"bot/conf"
+	"bot/entity/telegram"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrRecipientNotFound = errors.New("recipient not found")
+
+// RecipientStore holds the live recipient set, persisted to the recipients file on every change.
+type RecipientStore struct {
+	mu         sync.RWMutex
+	path       string
+	recipients []telegram.Recipient
+}
+
+// NewRecipientStore loads the recipients file at path; a missing file is an empty store, filled by /subscribe.
+func NewRecipientStore(path string) (*RecipientStore, error) {
+	recipients, err := conf.LoadRecipients(path)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("no recipients file at %s, starting without recipients", path)
+		return &RecipientStore{path: path}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range recipients {
+		skipMissedDelivery(&recipients[i], now)
+	}
+	return &RecipientStore{path: path, recipients: recipients}, nil
+}
+
+// skipMissedDelivery marks the daily calendar of a recipient never delivered as delivered up to now,
+// so the first one is sent at its delivery time rather than on the next run of the job.
+func skipMissedDelivery(recipient *telegram.Recipient, now time.Time) {
+	if recipient.LastDelivery != "" {
+		return
+	}
+	day, due := dueDelivery(*recipient, now)
+	if !due {
+		// the delivery time of today is still to come
+		day = previousDay(day)
+	}
+	recipient.LastDelivery = day
+}
+
+// previousDay returns the day before a YYYY-MM-DD day.
+func previousDay(day string) string {
+	t, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return day
+	}
+	return t.AddDate(0, 0, -1).Format("2006-01-02")
+}
+
+// All returns a copy of the current recipients, safe to range over while the store changes.
+func (r *RecipientStore) All() []telegram.Recipient {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	recipients := make([]telegram.Recipient, len(r.recipients))
+	copy(recipients, r.recipients)
+	return recipients
+}
+
+// Get returns the recipient of a chat and message thread.
+func (r *RecipientStore) Get(chatId int, messageThreadId int) (telegram.Recipient, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, recipient := range r.recipients {
+		if recipient.ChatId == chatId && recipient.MessageThreadId == messageThreadId {
+			return recipient, true
+		}
+	}
+	return telegram.Recipient{}, false
+}
+
+// Update applies update to the recipient of a chat and message thread and saves the store.
+func (r *RecipientStore) Update(chatId int, messageThreadId int, update func(recipient *telegram.Recipient)) (telegram.Recipient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.recipients {
+		if r.recipients[i].ChatId == chatId && r.recipients[i].MessageThreadId == messageThreadId {
+			updated := r.recipients[i]
+			update(&updated)
+			if err := r.save(replaceRecipient(r.recipients, i, updated)); err != nil {
+				return telegram.Recipient{}, err
+			}
+			return updated, nil
+		}
+	}
+	return telegram.Recipient{}, ErrRecipientNotFound
+}
+
+// Add subscribes a chat and message thread, returning false when it is already a recipient.
+func (r *RecipientStore) Add(recipient telegram.Recipient) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.recipients {
+		if existing.ChatId == recipient.ChatId && existing.MessageThreadId == recipient.MessageThreadId {
+			return false, nil
+		}
+	}
+	skipMissedDelivery(&recipient, time.Now())
+	recipients := make([]telegram.Recipient, len(r.recipients), len(r.recipients)+1)
+	copy(recipients, r.recipients)
+	if err := r.save(append(recipients, recipient)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Remove unsubscribes a chat and message thread, returning false when it was not a recipient.
+func (r *RecipientStore) Remove(chatId int, messageThreadId int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.recipients {
+		if existing.ChatId == chatId && existing.MessageThreadId == messageThreadId {
+			recipients := make([]telegram.Recipient, 0, len(r.recipients)-1)
+			recipients = append(recipients, r.recipients[:i]...)
+			recipients = append(recipients, r.recipients[i+1:]...)
+			if err := r.save(recipients); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// save persists the recipients and makes them the current set only when writing succeeds.
+func (r *RecipientStore) save(recipients []telegram.Recipient) error {
+	if err := conf.SaveRecipients(r.path, recipients); err != nil {
+		return err
+	}
+	r.recipients = recipients
+	return nil
+}
+
+func replaceRecipient(recipients []telegram.Recipient, i int, recipient telegram.Recipient) []telegram.Recipient {
+	replaced := make([]telegram.Recipient, len(recipients))
+	copy(replaced, recipients)
+	replaced[i] = recipient
+	return replaced
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"bot/entity/telegram"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSkipMissedDelivery(t *testing.T) {
+	tests := []struct {
+		name      string
+		recipient telegram.Recipient
+		now       time.Time
+		want      string
+	}{
+		{"default after 00:01 UTC", telegram.Recipient{}, time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC), "2025-03-10"},
+		{"default before 00:01 UTC", telegram.Recipient{}, time.Date(2025, 3, 10, 0, 0, 30, 0, time.UTC), "2025-03-09"},
+		{"local time to come", telegram.Recipient{Timezone: "Europe/Rome", DeliveryTime: "20:00"},
+			time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC), "2025-03-09"},
+		{"local time passed", telegram.Recipient{Timezone: "Europe/Rome", DeliveryTime: "08:00"},
+			time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC), "2025-03-10"},
+		{"local day behind UTC", telegram.Recipient{Timezone: "America/New_York", DeliveryTime: "21:00"},
+			time.Date(2025, 3, 1, 1, 0, 0, 0, time.UTC), "2025-02-27"},
+		{"already delivered", telegram.Recipient{LastDelivery: "2025-03-01"}, time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC), "2025-03-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipient := tt.recipient
+			skipMissedDelivery(&recipient, tt.now)
+			if recipient.LastDelivery != tt.want {
+				t.Errorf("LastDelivery = %s, want %s", recipient.LastDelivery, tt.want)
+			}
+			// nothing is due before the next delivery time
+			if _, due := dueDelivery(recipient, tt.now); due && tt.recipient.LastDelivery == "" {
+				t.Errorf("delivery due right after subscribing")
+			}
+		})
+	}
+}
+
+func TestRecipientStoreSubscribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recipients.json")
+	if err := os.WriteFile(path, []byte(`[{"chatId": 1, "messageThreadId": 0}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewRecipientStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, _ := store.Get(1, 0)
+	if loaded.LastDelivery == "" {
+		t.Errorf("loaded recipient without last delivery")
+	}
+
+	if _, err := store.Add(telegram.Recipient{ChatId: 2}); err != nil {
+		t.Fatal(err)
+	}
+	subscribed, _ := store.Get(2, 0)
+	if subscribed.LastDelivery == "" {
+		t.Errorf("subscribed recipient without last delivery")
+	}
+
+	// the unset filter fields are not written
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"timezone", "currencies", "minImpact", "deliveryTime", "null"} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("recipients file has %s: %s", field, data)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"bot/entity"
+	"github.com/enescakir/emoji"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var defaultReminderMinutes = []int{60, 15}
+
+// reminderScheduler keeps one timer for each event and reminder offset.
+type reminderScheduler struct {
+	mu     sync.Mutex
+	timers map[string]*scheduledReminder
+}
+
+type scheduledReminder struct {
+	at    time.Time
+	timer *time.Timer
+}
+
+func newReminderScheduler() *reminderScheduler {
+	return &reminderScheduler{timers: map[string]*scheduledReminder{}}
+}
+
+// reschedule aligns the timers with the refreshed events: new reminders are scheduled, the ones whose event
+// moved are rescheduled and the ones whose event disappeared are stopped.
+func (r *reminderScheduler) reschedule(events []entity.CalendarEvent, offsets []int, now time.Time,
+	send func(e entity.CalendarEvent, minutes int)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := map[string]bool{}
+	for _, e := range events {
+		date, err := e.Time()
+		if err != nil {
+			log.Printf("Error parsing date %s: %v", e.Date, err)
+			continue
+		}
+		for _, minutes := range offsets {
+			key := e.Key() + "|" + strconv.Itoa(minutes)
+			at := date.Add(-time.Duration(minutes) * time.Minute)
+
+			existing, ok := r.timers[key]
+			if ok && existing.at.Equal(at) {
+				wanted[key] = true
+				continue
+			}
+			if ok {
+				existing.timer.Stop()
+				delete(r.timers, key)
+				log.Printf("reminder %s moved from %s to %s", key, existing.at, at)
+			}
+			if at.Before(now) {
+				continue
+			}
+
+			event, offset := e, minutes
+			r.timers[key] = &scheduledReminder{
+				at: at,
+				timer: time.AfterFunc(at.Sub(now), func() {
+					send(event, offset)
+				}),
+			}
+			wanted[key] = true
+			log.Printf("reminder %s scheduled at %s", key, at)
+		}
+	}
+
+	for key, reminder := range r.timers {
+		if !wanted[key] {
+			reminder.timer.Stop()
+			delete(r.timers, key)
+		}
+	}
+}
+
+func (s service) PrepareReminderMessage(e entity.CalendarEvent, minutes int, loc *time.Location) string {
+	return emoji.AlarmClock.String() + " In " + strconv.Itoa(minutes) + " minutes: " + e.Event + "  " + GetEmojiCountry(e.Country) + "\n\n" +
+		emoji.Calendar.String() + "  DATE: " + formatEventDate(e, loc) + "\n" +
+		emoji.CurrencyExchange.String() + "  CURRENCY: " + e.Currency + "\n" +
+		emoji.VerticalTrafficLight.String() + "  IMPACT: " + e.Impact + "  " + GetEmojiSemaphore(e.Impact) + "\n" +
+		emoji.CrystalBall.String() + "  FORECAST: " + FormatEventValue(e.Forecast, e.Unit) + "\n" +
+		emoji.HourglassDone.String() + "  PREVIOUS: " + FormatEventValue(e.Previous, e.Unit)
+}
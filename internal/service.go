@@ -4,7 +4,9 @@ import (
 	"bot/conf"
 	"bot/entity"
 	"bot/entity/telegram"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/enescakir/emoji"
 	"github.com/go-co-op/gocron"
@@ -19,6 +21,8 @@ import (
 )
 
 type Service interface {
+	GetEconomicCalendar(from time.Time, to time.Time) ([]entity.CalendarEvent, error)
+
 	GetEconomicCalendarForNextDay(tomorrowDate time.Time) ([]entity.CalendarEvent, error)
 
 	GetXauRateFromYesterday(url string) (entity.FmpResponse, error)
@@ -29,58 +33,102 @@ type Service interface {
 
 	PrepareCommandNotFoundMessageToTelegramChat() string
 
-	SendTextToTelegramChat(chatId int, messageThreadId int, text string) (string, error)
+	SendTextToTelegramChat(chatId int, messageThreadId int, text string, parseMode string) ([]int, string, error)
 
-	ScheduledNewsNotification(recipients []telegram.Recipient)
+	ParseMode() string
 
-	ScheduledXauNotification(recipients []telegram.Recipient, spreadsheetId string, readRange string, sheetService *sheets.Service)
+	SendMenuToTelegramChat(chatId int, messageThreadId int, text string, keyboard telegram.InlineKeyboardMarkup) (string, error)
 
-	ScheduledXauSheetUpdate(recipients []telegram.Recipient, spreadsheetId string, readRange string, sheetId int, url string, sheetService *sheets.Service)
+	EditTelegramMessage(chatId int, messageId int, text string, keyboard telegram.InlineKeyboardMarkup) (string, error)
 
-	Readyz(recipients []telegram.Recipient)
-}
+	AnswerCallbackQuery(callbackQueryId string, text string) (string, error)
 
-type service struct {
-	config conf.Config
-}
+	PrepareSettingsMessage(recipient telegram.Recipient) string
 
-func NewService(config conf.Config) Service {
-	return service{config}
-}
+	PrepareSettingsKeyboard(recipient telegram.Recipient) telegram.InlineKeyboardMarkup
 
-func (s service) GetEconomicCalendarForNextDay(tomorrowDate time.Time) ([]entity.CalendarEvent, error) {
+	HandleSettingsCallback(query telegram.CallbackQuery) string
 
-	u, err := url.Parse(s.config.EconomicCalendarUrl)
-	if err != nil {
-		log.Fatal(err)
-	}
+	GetChatMember(chatId int, userId int) (telegram.ChatMember, error)
 
-	formattedTomorrowDate := tomorrowDate.Format("2006-01-02")
-	formattedCurrentDate := time.Now().Format("2006-01-02")
+	GetUpdates(ctx context.Context, offset int, timeout int) ([]telegram.Update, error)
 
-	q := u.Query()
-	q.Set("from", formattedCurrentDate)
-	q.Set("to", formattedTomorrowDate)
-	q.Set("apikey", s.config.EconomicCalendarApyKey)
+	SetWebhook(ctx context.Context, webhookUrl string, secretToken string, maxConnections int) error
 
-	u.RawQuery = q.Encode()
-	log.Println("Calling " + u.String())
+	GetWebhookInfo(ctx context.Context) (telegram.WebhookInfo, error)
 
-	response, err := http.Get(u.String())
-	if err != nil {
-		log.Printf("error while calling Economic Calendar %s", err.Error())
-		return []entity.CalendarEvent{}, err
-	}
-	log.Println(response.Status)
+	DeleteWebhook(ctx context.Context) error
 
-	var events []entity.CalendarEvent
-	body, err := io.ReadAll(response.Body)
-	if err := json.Unmarshal(body, &events); err != nil {
-		log.Printf("error while parsing Economic Calendar response %s", err.Error())
-		panic(err)
-	}
+	SetMyCommands(commands []telegram.BotCommand, scope telegram.BotCommandScope, languageCode string) (string, error)
+
+	AnswerInlineQuery(inlineQueryId string, results []telegram.InlineQueryResultArticle) (string, error)
+
+	PrepareInlineQueryResults(query telegram.InlineQuery) []telegram.InlineQueryResultArticle
+
+	UpdateRecipient(chatId int, messageThreadId int, update func(recipient *telegram.Recipient)) (telegram.Recipient, error)
+
+	Subscribe(recipient telegram.Recipient) (bool, error)
+
+	Unsubscribe(chatId int, messageThreadId int) (bool, error)
+
+	PrepareRecipientFilterMessage(recipient telegram.Recipient) string
+
+	GetRecipient(chatId int, messageThreadId int) telegram.Recipient
+
+	GetSubscribedRecipient(chatId int, messageThreadId int) (telegram.Recipient, bool)
+
+	PrepareCachedCalendarMessage(recipient telegram.Recipient, day time.Time) string
+
+	PrepareCentralBanksMessage(loc *time.Location) string
+
+	PrepareHistoryMessage(query string, loc *time.Location) string
+
+	PrepareXauImpactMessage(query string) string
+
+	ScheduledNewsNotification()
+
+	ScheduledSurpriseNotification()
+
+	ScheduledCalendarRefresh()
+
+	ScheduledWeeklyOutlook()
+
+	ScheduledXauNotification(spreadsheetId string, readRange string, sheetService *sheets.Service)
+
+	ScheduledXauSheetUpdate(spreadsheetId string, readRange string, sheetId int, url string, sheetService *sheets.Service)
+
+	Readyz()
+}
 
-	return events, nil
+type service struct {
+	config     conf.Config
+	provider   CalendarProvider
+	recipients *RecipientStore
+	surprises  *surpriseTracker
+	reminders  *reminderScheduler
+	snapshot   *calendarSnapshot
+	cache      *calendarCache
+	holidays   HolidayCalendar
+	history    *HistoryStore
+	sheets     *sheets.Service
+}
+
+// XAUUSD sessions follow the US market holidays
+const xauMarket = "US"
+
+// NewService wires the service; history may be nil when no history file is configured.
+func NewService(config conf.Config, provider CalendarProvider, recipients *RecipientStore, holidays HolidayCalendar,
+	history *HistoryStore, sheetService *sheets.Service) Service {
+	return service{config, provider, recipients, newSurpriseTracker(history), newReminderScheduler(), newCalendarSnapshot(),
+		newCalendarCache(config.CalendarCacheFile), holidays, history, sheetService}
+}
+
+func (s service) GetEconomicCalendar(from time.Time, to time.Time) ([]entity.CalendarEvent, error) {
+	return s.provider.GetEvents(from, to)
+}
+
+func (s service) GetEconomicCalendarForNextDay(tomorrowDate time.Time) ([]entity.CalendarEvent, error) {
+	return s.GetEconomicCalendar(time.Now(), tomorrowDate)
 }
 
 func (s service) GetXauRateFromYesterday(providerUrl string) (entity.FmpResponse, error) {
@@ -107,17 +155,80 @@ func (s service) GetXauRateFromYesterday(providerUrl string) (entity.FmpResponse
 	return fmpResponse, nil
 }
 
-func (s service) SendTextToTelegramChat(chatId int, messageThreadId int, text string) (string, error) {
-
-	log.Printf("Sending %s to chat_id: %d", text, chatId)
-	response, err := http.PostForm(
-		s.config.TelegramApiBaseUrl+s.config.TelegramBotToken+s.config.TelegramApiSendMessage,
-		url.Values{
+// SendTextToTelegramChat sends text, formatted in parseMode or plain when empty, and returns the ids of the messages sent
+// with the last response body. A text over the Telegram limit is sent in numbered parts, in order.
+func (s service) SendTextToTelegramChat(chatId int, messageThreadId int, text string, parseMode string) ([]int, string, error) {
+	var messageIds []int
+	var telegramResponseBody string
+	for _, part := range splitMessage(text, parseMode) {
+		log.Printf("Sending %s to chat_id: %d", part, chatId)
+		values := url.Values{
 			"chat_id":           {strconv.Itoa(chatId)},
 			"message_thread_id": {strconv.Itoa(messageThreadId)},
 			"via_bot":           {"@EconomicCalendarAndNewsBot"},
-			"text":              {text},
+			"text":              {part},
+		}
+		if parseMode != "" {
+			values.Set("parse_mode", parseMode)
+		}
+
+		body, err := s.postToTelegram(s.config.TelegramApiSendMessage, values)
+		telegramResponseBody = body
+		if err != nil {
+			return messageIds, telegramResponseBody, err
+		}
+		messageId, err := sentMessageId(body)
+		if err != nil {
+			return messageIds, telegramResponseBody, err
+		}
+		messageIds = append(messageIds, messageId)
+	}
+	return messageIds, telegramResponseBody, nil
+}
+
+// sentMessageId reads the id of the message sent from a sendMessage response body.
+func sentMessageId(body string) (int, error) {
+	var response telegram.Response
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		return 0, err
+	}
+	if !response.Ok {
+		return 0, fmt.Errorf("sendMessage failed with code %d: %s", response.ErrorCode, response.Description)
+	}
+	var message telegram.Message
+	if err := json.Unmarshal(response.Result, &message); err != nil {
+		return 0, err
+	}
+	return message.MessageId, nil
+}
+
+// ParseMode is the parse mode of the formatted messages, e.g. the calendar, empty for plain text.
+func (s service) ParseMode() string {
+	return s.config.ParseMode
+}
+
+// SetMyCommands replaces the commands of the Telegram menu of scope, for users of languageCode or every user when empty.
+func (s service) SetMyCommands(commands []telegram.BotCommand, scope telegram.BotCommandScope, languageCode string) (string, error) {
+	encodedCommands, err := json.Marshal(commands)
+	if err != nil {
+		return "", err
+	}
+	encodedScope, err := json.Marshal(scope)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Setting %d commands for scope %s and language %q", len(commands), scope.Type, languageCode)
+	return s.postToTelegram("/setMyCommands",
+		url.Values{
+			"commands":      {string(encodedCommands)},
+			"scope":         {string(encodedScope)},
+			"language_code": {languageCode},
 		})
+}
+
+// postToTelegram calls a Bot API method, e.g. "/sendMessage", and returns the response body.
+func (s service) postToTelegram(method string, values url.Values) (string, error) {
+	response, err := http.PostForm(s.config.TelegramApiBaseUrl+s.config.TelegramBotToken+method, values)
 
 	if err != nil {
 		log.Printf("error when posting text to the chat: %s", err.Error())
@@ -141,38 +252,115 @@ func (s service) SendTextToTelegramChat(chatId int, messageThreadId int, text st
 	return bodyString, nil
 }
 
-func (s service) PrepareXauMessage(short float64, long float64) string {
-	return emoji.Butter.String() + " XAUUSD " + time.Now().Weekday().String() + " statistics: \n\n" +
-		emoji.GreenCircle.String() + " LONG " + strconv.FormatFloat(long, 'f', -1, 32) + "% \n\n" +
-		emoji.RedCircle.String() + " SHORT " + strconv.FormatFloat(short, 'f', -1, 32) + "% \n\n" +
-		"Last update: " + time.Now().String() + " Day: " + strconv.Itoa(int(time.Now().Weekday()))
+// PrepareXauMessage is formatted in the configured parse mode.
+func (s service) PrepareXauMessage(short float64, long float64, loc *time.Location) string {
+	f := s.formatter()
+	return emoji.Butter.String() + " " + f.Bold("XAUUSD") + f.Escape(" "+time.Now().In(loc).Weekday().String()+" statistics: ") + "\n\n" +
+		emoji.GreenCircle.String() + f.Escape(" LONG ") + f.Code(strconv.FormatFloat(long, 'f', -1, 32)+"%") + " \n\n" +
+		emoji.RedCircle.String() + f.Escape(" SHORT ") + f.Code(strconv.FormatFloat(short, 'f', -1, 32)+"%") + " \n\n" +
+		f.Escape("Last update: ") + f.Code(formatLastUpdate(loc))
 }
 
-func (s service) PrepareXauUpdateMessage() string {
+func (s service) PrepareXauUpdateMessage(loc *time.Location) string {
 	return emoji.Butter.String() + "XAUUSD DAILY FILE UPDATED :) \n\n" +
-		"Last update: " + time.Now().String() + " Day: " + strconv.Itoa(int(time.Now().Weekday()))
+		"Last update: " + formatLastUpdate(loc)
 }
 
+// PrepareEconomicCalendarForNextDayMessage is formatted in the configured parse mode.
 func (s service) PrepareEconomicCalendarForNextDayMessage(tomorrowDate time.Time, events []entity.CalendarEvent) string {
+	f := s.formatter()
 
 	formattedTomorrowDate := tomorrowDate.Format("2006-01-02")
-	message := "Calendario Economico del " + formattedTomorrowDate + " \n\n"
+	message := f.Bold("Calendario Economico del "+formattedTomorrowDate) + " \n\n"
 
 	if len(events) == 0 {
-		message = message + "Nessuna Notizia Rilevante :("
+		message = message + f.Escape("Nessuna Notizia Rilevante :(")
 	} else {
 		for _, e := range events {
-			message = message +
-				emoji.Calendar.String() + "  DATE: " + e.Date + "\n" +
-				emoji.Megaphone.String() + "  EVENT: " + e.Event + "\n" +
-				emoji.GlobeShowingEuropeAfrica.String() + "  COUNTRY: " + e.Country + "  " + GetEmojiCountry(e.Country) + "\n" +
-				emoji.CurrencyExchange.String() + "  CURRENCY: " + e.Currency + "\n" +
-				emoji.VerticalTrafficLight.String() + "  IMPACT: " + e.Impact + "  " + GetEmojiSemaphore(e.Impact) + "\n\n"
+			if bank, ok := rateDecisionBank(e); ok {
+				message = message + f.Escape(s.PrepareRateDecisionMessage(bank, e, tomorrowDate.Location()))
+				continue
+			}
+			message = message + s.PrepareEventMessage(e, tomorrowDate.Location()) + "\n\n"
 		}
 	}
 	return message
 }
 
+// PrepareEventMessage renders the details of an event, with times in loc, formatted in the configured parse mode.
+func (s service) PrepareEventMessage(e entity.CalendarEvent, loc *time.Location) string {
+	f := s.formatter()
+	message := emoji.Calendar.String() + f.Escape("  DATE: ") + f.Code(formatEventDate(e, loc)) + "\n" +
+		emoji.Megaphone.String() + f.Escape("  EVENT: ") + f.Bold(e.Event) + "\n" +
+		emoji.GlobeShowingEuropeAfrica.String() + f.Escape("  COUNTRY: "+e.Country+"  ") + GetEmojiCountry(e.Country) + "\n" +
+		emoji.CurrencyExchange.String() + f.Escape("  CURRENCY: "+e.Currency) + "\n" +
+		emoji.VerticalTrafficLight.String() + f.Escape("  IMPACT: "+e.Impact+"  ") + GetEmojiSemaphore(e.Impact) + "\n"
+	if e.Actual != nil {
+		message = message + emoji.BarChart.String() + f.Escape("  ACTUAL: ") + f.Code(FormatEventValue(e.Actual, e.Unit)) + "\n"
+	}
+	return message +
+		emoji.CrystalBall.String() + f.Escape("  FORECAST: ") + f.Code(FormatEventValue(e.Forecast, e.Unit)) + "\n" +
+		emoji.HourglassDone.String() + f.Escape("  PREVIOUS: ") + f.Code(FormatEventValue(e.Previous, e.Unit))
+}
+
+// FormatEventValue renders a released, forecast or previous value with its unit, "-" when not available.
+func FormatEventValue(value *float64, unit string) string {
+	if value == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64) + unit
+}
+
+func (s service) UpdateRecipient(chatId int, messageThreadId int, update func(recipient *telegram.Recipient)) (telegram.Recipient, error) {
+	return s.recipients.Update(chatId, messageThreadId, update)
+}
+
+func (s service) Subscribe(recipient telegram.Recipient) (bool, error) {
+	return s.recipients.Add(recipient)
+}
+
+func (s service) Unsubscribe(chatId int, messageThreadId int) (bool, error) {
+	return s.recipients.Remove(chatId, messageThreadId)
+}
+
+func (s service) PrepareRecipientFilterMessage(recipient telegram.Recipient) string {
+	return emoji.Gear.String() + " Notification filter for this chat:\n\n" +
+		emoji.CurrencyExchange.String() + "  CURRENCIES: " + strings.Join(recipientCurrencies(recipient), ", ") + "\n" +
+		emoji.VerticalTrafficLight.String() + "  MIN IMPACT: " + recipientMinImpact(recipient) + "  " + GetEmojiSemaphore(recipientMinImpact(recipient)) + "\n\n" +
+		"Change them with /currencies USD,EUR and /minimpact Low|Medium|High"
+}
+
+// GetRecipient returns the recipient of a chat, or a recipient with the default settings when the chat is not one.
+func (s service) GetRecipient(chatId int, messageThreadId int) telegram.Recipient {
+	recipient, ok := s.recipients.Get(chatId, messageThreadId)
+	if !ok {
+		return telegram.Recipient{ChatId: chatId, MessageThreadId: messageThreadId}
+	}
+	return recipient
+}
+
+func (s service) GetSubscribedRecipient(chatId int, messageThreadId int) (telegram.Recipient, bool) {
+	return s.recipients.Get(chatId, messageThreadId)
+}
+
+// PrepareCachedCalendarMessage renders the cached events of a day, in the recipient timezone, passing its filter.
+// It is formatted in the configured parse mode.
+func (s service) PrepareCachedCalendarMessage(recipient telegram.Recipient, day time.Time) string {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	events, ok := s.cache.eventsBetween(start, start.AddDate(0, 0, 1))
+	if !ok {
+		return emoji.CrossMark.String() + s.formatter().Escape(" No calendar available for "+start.Format("2006-01-02")+" yet")
+	}
+
+	var eventsFiltered []entity.CalendarEvent
+	for _, e := range events {
+		if acceptsEvent(recipient, e) {
+			eventsFiltered = append(eventsFiltered, e)
+		}
+	}
+	return s.PrepareEconomicCalendarForNextDayMessage(start, eventsFiltered)
+}
+
 func GetEmojiCountry(country string) string {
 	switch country {
 	case "UK":
@@ -201,14 +389,24 @@ func GetEmojiSemaphore(impact string) string {
 	}
 }
 
-func (s service) ScheduledNewsNotification(recipients []telegram.Recipient) {
-	var message string
+func (s service) ScheduledNewsNotification() {
 	s1 := gocron.NewScheduler(time.UTC)
-	_, err := s1.Every(1).Day().At("00:01").Do(func() {
-		//TODO API NOT VALID ANYMORE - FIND ANOTHER FREE SERVICE
+	// every minute, for the recipients whose delivery time has passed without a delivery yet
+	_, err := s1.Cron("* * * * *").Do(func() {
+		now := time.Now()
+
+		var due []telegram.Recipient
+		for _, recipient := range s.recipients.All() {
+			if _, ok := dueDelivery(recipient, now); ok {
+				due = append(due, recipient)
+			}
+		}
+		if len(due) == 0 {
+			return
+		}
 
-		/* Add 1 day to the current date to get tomorrow's date
-		tomorrowDate := time.Now().AddDate(0, 0, 1)
+		// Add 2 days to the current date, the range covers tomorrow in every timezone
+		tomorrowDate := now.UTC().AddDate(0, 0, 2)
 
 		events, err := s.GetEconomicCalendarForNextDay(tomorrowDate)
 		if err != nil {
@@ -216,44 +414,53 @@ func (s service) ScheduledNewsNotification(recipients []telegram.Recipient) {
 			return
 		}
 
-		var eventsFiltered []entity.CalendarEvent
-		for _, e := range events {
-			if e.Currency == "EUR" || e.Currency == "GBP" || e.Currency == "USD" || e.Currency == "JPY" {
-				if e.Impact == "High" { //|| e.Impact == "Medium" {
-
-					parsedDate, err := time.Parse("2006-01-02 15:04:05", e.Date)
-					if err != nil {
-						fmt.Println("Error parsing date:", err)
-						return
-					}
-
-					if tomorrowDate.Year() == parsedDate.Year() &&
-						tomorrowDate.Month() == parsedDate.Month() &&
-						tomorrowDate.Day() == parsedDate.Day() {
-						eventsFiltered = append(eventsFiltered, e)
+		// recipients sharing timezone and filter get the same message
+		messages := map[string]string{}
+		for _, recipient := range due {
+			message, ok := messages[filterKey(recipient)]
+			if !ok {
+				start, end := nextDay(now, loadLocation(recipient.Timezone))
+
+				var eventsFiltered []entity.CalendarEvent
+				for _, e := range events {
+					if acceptsEvent(recipient, e) {
+
+						parsedDate, err := e.Time()
+						if err != nil {
+							log.Printf("Error parsing date %s: %v", e.Date, err)
+							continue
+						}
+
+						if !parsedDate.Before(start) && parsedDate.Before(end) {
+							eventsFiltered = append(eventsFiltered, e)
+						}
 					}
 				}
-			}
-		}
-
-		message = s.PrepareEconomicCalendarForNextDayMessage(tomorrowDate, eventsFiltered)*/
 
-		message = "Economic Calendar -> Special Endpoint : This endpoint is not available under your current subscription " +
-			"please visit our subscription page to upgrade your plan " +
-			"at https://site.financialmodelingprep.com/developer/docs/pricing"
+				message = s.formatter().Escape(s.PrepareMarketClosuresMessage(recipient, start)) +
+					s.PrepareEconomicCalendarForNextDayMessage(start, eventsFiltered)
+				messages[filterKey(recipient)] = message
+			}
 
-		for _, recipient := range recipients {
 			// Send the punchline back to Telegram
 			log.Printf("send to chatId, %s", strconv.Itoa(recipient.ChatId))
-			telegramResponseBody, err := s.SendTextToTelegramChat(recipient.ChatId, recipient.MessageThreadId, message)
+			_, telegramResponseBody, err := s.SendTextToTelegramChat(recipient.ChatId, recipient.MessageThreadId, message, s.config.ParseMode)
 			if err != nil {
 				log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
 			} else {
 				log.Printf("economic calendar successfully distributed to chat id %d", recipient.ChatId)
 			}
+
+			// a failed delivery is not retried, so an unreachable chat is not sent the calendar every minute
+			day, _ := dueDelivery(recipient, now)
+			_, err = s.recipients.Update(recipient.ChatId, recipient.MessageThreadId, func(recipient *telegram.Recipient) {
+				recipient.LastDelivery = day
+			})
+			if err != nil && !errors.Is(err, ErrRecipientNotFound) {
+				log.Printf("could not save the delivery to chat id %d: %s", recipient.ChatId, err.Error())
+			}
 		}
 
-		message = ""
 	})
 	s1.StartAsync()
 	if err != nil {
@@ -263,13 +470,87 @@ func (s service) ScheduledNewsNotification(recipients []telegram.Recipient) {
 	log.Printf("next run at: %s", t)
 }
 
-func (s service) ScheduledXauSheetUpdate(recipients []telegram.Recipient, spreadsheetId string,
+// PrepareMarketClosuresMessage announces the markets of the recipient currencies closed tomorrow, empty when none.
+func (s service) PrepareMarketClosuresMessage(recipient telegram.Recipient, tomorrowDate time.Time) string {
+	message := ""
+	for _, market := range holidayMarkets {
+		holiday, ok := s.holidays.Holiday(market, tomorrowDate)
+		if !ok || !acceptsMarket(recipient, market) {
+			continue
+		}
+		message = message + emoji.ClassicalBuilding.String() + " " + market + " markets closed tomorrow " +
+			GetEmojiCountry(market) + " (" + holiday.Name + ")\n"
+	}
+	if message != "" {
+		message = message + "\n"
+	}
+	return message
+}
+
+// dueDelivery tells whether the daily calendar is due to the recipient at now: its delivery time has passed
+// and nothing was delivered yet on the day of now. The day is returned in the timezone of the delivery time.
+func dueDelivery(recipient telegram.Recipient, now time.Time) (string, bool) {
+	loc, deliveryTime := time.UTC, defaultDeliveryTime
+	if recipient.DeliveryTime != "" {
+		loc, deliveryTime = loadLocation(recipient.Timezone), recipient.DeliveryTime
+	}
+	local := now.In(loc)
+	day := local.Format("2006-01-02")
+	// HH:MM times compare as strings
+	return day, local.Format("15:04") >= deliveryTime && recipient.LastDelivery != day
+}
+
+// nextDay returns the start and the end of the day after now in the given location.
+func nextDay(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	local := now.In(loc)
+	start := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// formatEventDate renders the event time in the given location, falling back to the raw UTC date.
+func formatEventDate(e entity.CalendarEvent, loc *time.Location) string {
+	t, err := e.Time()
+	if err != nil {
+		return e.Date
+	}
+	return t.In(loc).Format("2006-01-02 15:04 MST")
+}
+
+func formatLastUpdate(loc *time.Location) string {
+	now := time.Now().In(loc)
+	return now.Format("2006-01-02 15:04:05 MST") + " Day: " + strconv.Itoa(int(now.Weekday()))
+}
+
+// findHistorical returns the daily XAU candle of the date of day.
+func findHistorical(response entity.FmpResponse, day time.Time) (entity.FmpHistorical, bool) {
+	for _, historical := range response.Historical {
+		if historical.Date == day.Format("2006-01-02") {
+			return historical, true
+		}
+	}
+	return entity.FmpHistorical{}, false
+}
+
+func (s service) ScheduledXauSheetUpdate(spreadsheetId string,
 	writeRange string, sheetId int, url string, sheetService *sheets.Service) {
 	var message string
 	s1 := gocron.NewScheduler(time.UTC)
 	_, err := s1.Every(1).Day().At("00:03").Do(func() {
-		//No update on sunday and monday
-		if time.Now().Weekday() != 0 && time.Now().Weekday() != 1 {
+		// Update only when yesterday was a trading session: no update on weekends and market holidays
+		today := time.Now().UTC()
+		yesterday := today.AddDate(0, 0, -1)
+		if s.holidays.PreviousSession(xauMarket, today).Equal(yesterday) {
+
+			response, err := s.GetXauRateFromYesterday(url)
+			if err != nil {
+				log.Fatalf("Unable to get xau data: %v", err)
+			}
+
+			session, ok := findHistorical(response, yesterday)
+			if !ok {
+				log.Printf("no xau data for session %s", yesterday.Format("2006-01-02"))
+				return
+			}
 
 			insertRequest := &sheets.Request{
 				InsertDimension: &sheets.InsertDimensionRequest{
@@ -287,41 +568,23 @@ func (s service) ScheduledXauSheetUpdate(recipients []telegram.Recipient, spread
 				Requests: []*sheets.Request{insertRequest},
 			}
 
-			_, err := sheetService.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Do()
+			_, err = sheetService.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Do()
 			if err != nil {
 				log.Fatalf("Unable to insert row: %v", err)
 			}
 
-			response, err := s.GetXauRateFromYesterday(url)
-			if err != nil {
-				log.Fatalf("Unable to get xau data: %v", err)
-			}
-			yesterday := time.Now().AddDate(0, 0, -1)
 			formattedYesterday := yesterday.Format("02/01/2006")
 
-			var values []interface{}
 			// Definisci i dati da inserire
-			if time.Now().Weekday() == 6 {
-				values = []interface{}{
-					formattedYesterday,
-					response.Historical[0].Close,
-					response.Historical[0].Open,
-					response.Historical[0].High,
-					response.Historical[0].Low,
-					response.Historical[0].Volume,
-					0, //response.Historical[1].ChangePercent, //TODO
-					"@EconomicCalendarAndNewsBot"}
-			} else {
-				values = []interface{}{
-					formattedYesterday,
-					response.Historical[1].Close,
-					response.Historical[1].Open,
-					response.Historical[1].High,
-					response.Historical[1].Low,
-					response.Historical[1].Volume,
-					0, //response.Historical[1].ChangePercent, //TODO
-					"@EconomicCalendarAndNewsBot"}
-			}
+			values := []interface{}{
+				formattedYesterday,
+				session.Close,
+				session.Open,
+				session.High,
+				session.Low,
+				session.Volume,
+				0, //session.ChangePercent, //TODO
+				"@EconomicCalendarAndNewsBot"}
 
 			valueRange := &sheets.ValueRange{
 				Range:  writeRange,
@@ -336,12 +599,11 @@ func (s service) ScheduledXauSheetUpdate(recipients []telegram.Recipient, spread
 
 			fmt.Println("Riga inserita con successo alla seconda posizione")
 
-			message = s.PrepareXauUpdateMessage()
-			log.Printf(message)
-			for _, recipient := range recipients {
+			for _, recipient := range s.recipients.All() {
+				message = s.PrepareXauUpdateMessage(loadLocation(recipient.Timezone))
 				// Send the punchline back to Telegram
 				log.Printf("send to chatId, %s", strconv.Itoa(recipient.ChatId))
-				telegramResponseBody, err := s.SendTextToTelegramChat(recipient.ChatId, recipient.MessageThreadId, message)
+				_, telegramResponseBody, err := s.SendTextToTelegramChat(recipient.ChatId, recipient.MessageThreadId, message, "")
 				if err != nil {
 					log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
 				} else {
@@ -360,8 +622,15 @@ func (s service) ScheduledXauSheetUpdate(recipients []telegram.Recipient, spread
 	log.Printf("next run at: %s", t)
 }
 
+// parseSheetNumber parses the numbers of the XAU sheet, formatted with the Italian locale (e.g. 2.345,67).
+func parseSheetNumber(value string) float64 {
+	value = strings.ReplaceAll(value, ".", "")
+	value = strings.ReplaceAll(value, ",", ".")
+	number, _ := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	return number
+}
+
 func (s service) ScheduledXauNotification(
-	recipients []telegram.Recipient,
 	spreadsheetId string, readRange string, sheetService *sheets.Service) {
 	var message string
 	s1 := gocron.NewScheduler(time.UTC)
@@ -392,13 +661,8 @@ func (s service) ScheduledXauNotification(
 				log.Printf("Unable to parse date: %v", err)
 			}
 
-			closeStr = strings.ReplaceAll(closeStr, ".", "")
-			openStr = strings.ReplaceAll(openStr, ".", "")
-			closeStr = strings.ReplaceAll(closeStr, ",", ".")
-			openStr = strings.ReplaceAll(openStr, ",", ".")
-
-			closeFloat, _ := strconv.ParseFloat(strings.TrimSpace(closeStr), 64)
-			openFloat, _ := strconv.ParseFloat(strings.TrimSpace(openStr), 64)
+			closeFloat := parseSheetNumber(closeStr)
+			openFloat := parseSheetNumber(openStr)
 
 			// Controlla se è oggi
 			if date.Weekday() == time.Now().Weekday() {
@@ -415,12 +679,11 @@ func (s service) ScheduledXauNotification(
 		longPer := (float64(long) / float64(total)) * 100
 		shortPer := (float64(short) / float64(total)) * 100
 
-		message = s.PrepareXauMessage(longPer, shortPer)
-		log.Printf(message)
-		for _, recipient := range recipients {
+		for _, recipient := range s.recipients.All() {
+			message = s.PrepareXauMessage(longPer, shortPer, loadLocation(recipient.Timezone))
 			// Send the punchline back to Telegram
 			log.Printf("send to chatId, %s", strconv.Itoa(recipient.ChatId))
-			telegramResponseBody, err := s.SendTextToTelegramChat(recipient.ChatId, recipient.MessageThreadId, message)
+			_, telegramResponseBody, err := s.SendTextToTelegramChat(recipient.ChatId, recipient.MessageThreadId, message, s.config.ParseMode)
 			if err != nil {
 				log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
 			} else {
@@ -438,15 +701,15 @@ func (s service) ScheduledXauNotification(
 	log.Printf("next run at: %s", t)
 }
 
-func (s service) Readyz(recipients []telegram.Recipient) {
+func (s service) Readyz() {
 	var message string
 	s2 := gocron.NewScheduler(time.UTC)
 	_, err := s2.Every(1).Day().At("23:59").Do(func() {
 		message = "EconomicCalendarAndNewsBot Running " + emoji.BeamingFaceWithSmilingEyes.String()
-		for _, recipient := range recipients {
+		for _, recipient := range s.recipients.All() {
 			// Send the punchline back to Telegram
 			log.Printf("send to chatId, %s", strconv.Itoa(recipient.ChatId))
-			telegramResponseBody, err := s.SendTextToTelegramChat(recipient.ChatId, recipient.MessageThreadId, message)
+			_, telegramResponseBody, err := s.SendTextToTelegramChat(recipient.ChatId, recipient.MessageThreadId, message, "")
 			if err != nil {
 				log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
 			} else {
@@ -462,14 +725,16 @@ func (s service) Readyz(recipients []telegram.Recipient) {
 	log.Printf("next run at: %s", t)
 }
 
+// PrepareStartMessageToTelegramChat is formatted in the configured parse mode.
 func (s service) PrepareStartMessageToTelegramChat() string {
-	return emoji.WavingHand.String() + " Hi! @EconomicCalendarAndNewsBot here!" + "\n\n" +
-		"Do you want " + emoji.Butter.String() + " some XAUUSD statistcs? " + emoji.RelievedFace.String() + "\n\n" +
-		"Check the command list! " + emoji.CheckMarkButton.String() + "\n\n" +
-		"Made by @mariocanalella" + emoji.Sparkles.String()
+	f := s.formatter()
+	return emoji.WavingHand.String() + f.Escape(" Hi! ") + f.Bold("@EconomicCalendarAndNewsBot") + f.Escape(" here!") + "\n\n" +
+		f.Escape("Do you want ") + emoji.Butter.String() + f.Escape(" some ") + f.Bold("XAUUSD") + f.Escape(" statistcs? ") + emoji.RelievedFace.String() + "\n\n" +
+		f.Escape("Check the command list with /help! ") + emoji.CheckMarkButton.String() + "\n\n" +
+		f.Escape("Made by @mariocanalella") + emoji.Sparkles.String()
 }
 
 func (s service) PrepareCommandNotFoundMessageToTelegramChat() string {
 	return emoji.CrossMark.String() + "Command not found " + emoji.SadButRelievedFace.String() + "\n\n" +
-		"Check the command list! " + emoji.Eyes.String()
+		"Check the command list with /help! " + emoji.Eyes.String()
 }
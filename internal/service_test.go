@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"bot/entity/telegram"
+	"testing"
+	"time"
+)
+
+func TestDueDelivery(t *testing.T) {
+	tests := []struct {
+		name      string
+		recipient telegram.Recipient
+		now       time.Time
+		day       string
+		due       bool
+	}{
+		{"default before 00:01 UTC", telegram.Recipient{}, time.Date(2025, 3, 10, 0, 0, 30, 0, time.UTC), "2025-03-10", false},
+		{"default at 00:01 UTC", telegram.Recipient{}, time.Date(2025, 3, 10, 0, 1, 0, 0, time.UTC), "2025-03-10", true},
+		{"default tick missed", telegram.Recipient{}, time.Date(2025, 3, 10, 0, 7, 0, 0, time.UTC), "2025-03-10", true},
+		{"default already delivered", telegram.Recipient{LastDelivery: "2025-03-10"}, time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC), "2025-03-10", false},
+		{"default delivered yesterday", telegram.Recipient{LastDelivery: "2025-03-09"}, time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC), "2025-03-10", true},
+		{"local time not reached", telegram.Recipient{Timezone: "Europe/Rome", DeliveryTime: "08:00"},
+			time.Date(2025, 3, 10, 6, 59, 0, 0, time.UTC), "2025-03-10", false},
+		{"local time reached", telegram.Recipient{Timezone: "Europe/Rome", DeliveryTime: "08:00"},
+			time.Date(2025, 3, 10, 7, 0, 0, 0, time.UTC), "2025-03-10", true},
+		{"local day ahead of UTC", telegram.Recipient{Timezone: "Asia/Tokyo", DeliveryTime: "07:00", LastDelivery: "2025-03-10"},
+			time.Date(2025, 3, 10, 22, 30, 0, 0, time.UTC), "2025-03-11", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day, due := dueDelivery(tt.recipient, tt.now)
+			if day != tt.day || due != tt.due {
+				t.Errorf("dueDelivery = %s %t, want %s %t", day, due, tt.day, tt.due)
+			}
+		})
+	}
+}
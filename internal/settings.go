@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"bot/entity/telegram"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/enescakir/emoji"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const settingsCallbackPrefix = "settings:"
+
+// the UTC time of the daily calendar of the recipients without a delivery time
+const defaultDeliveryTime = "00:01"
+
+// choices offered by the /settings menu
+var (
+	settingsCurrencies    = []string{"USD", "EUR", "GBP", "JPY", "CAD", "AUD", "NZD", "CHF", "CNY"}
+	settingsImpacts       = []string{"Low", "Medium", "High"}
+	settingsDeliveryTimes = []string{"", "07:00", "08:00", "18:00", "21:00"}
+)
+
+func (s service) SendMenuToTelegramChat(chatId int, messageThreadId int, text string, keyboard telegram.InlineKeyboardMarkup) (string, error) {
+	markup, err := json.Marshal(keyboard)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Sending menu %s to chat_id: %d", text, chatId)
+	return s.postToTelegram(s.config.TelegramApiSendMessage,
+		url.Values{
+			"chat_id":           {strconv.Itoa(chatId)},
+			"message_thread_id": {strconv.Itoa(messageThreadId)},
+			"text":              {text},
+			"reply_markup":      {string(markup)},
+		})
+}
+
+// EditTelegramMessage replaces the text and the inline keyboard of a message already sent.
+func (s service) EditTelegramMessage(chatId int, messageId int, text string, keyboard telegram.InlineKeyboardMarkup) (string, error) {
+	markup, err := json.Marshal(keyboard)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Editing message %d of chat_id: %d", messageId, chatId)
+	return s.postToTelegram("/editMessageText",
+		url.Values{
+			"chat_id":      {strconv.Itoa(chatId)},
+			"message_id":   {strconv.Itoa(messageId)},
+			"text":         {text},
+			"reply_markup": {string(markup)},
+		})
+}
+
+// AnswerCallbackQuery stops the button loading animation, showing text as a notification when not empty.
+func (s service) AnswerCallbackQuery(callbackQueryId string, text string) (string, error) {
+	return s.postToTelegram("/answerCallbackQuery",
+		url.Values{
+			"callback_query_id": {callbackQueryId},
+			"text":              {text},
+		})
+}
+
+func (s service) PrepareSettingsMessage(recipient telegram.Recipient) string {
+	return s.PrepareRecipientFilterMessage(recipient) + "\n" +
+		emoji.AlarmClock.String() + "  DAILY CALENDAR AT: " + formatDeliveryTime(recipient.DeliveryTime) + "\n\n" +
+		"Tap the buttons to change them"
+}
+
+func formatDeliveryTime(deliveryTime string) string {
+	if deliveryTime == "" {
+		return defaultDeliveryTime + " UTC"
+	}
+	return deliveryTime
+}
+
+// PrepareSettingsKeyboard builds the /settings menu, marking the current choices of the recipient.
+func (s service) PrepareSettingsKeyboard(recipient telegram.Recipient) telegram.InlineKeyboardMarkup {
+	var keyboard telegram.InlineKeyboardMarkup
+
+	var row []telegram.InlineKeyboardButton
+	for i, currency := range settingsCurrencies {
+		text := currency
+		if acceptsCurrency(recipient, currency) {
+			text = emoji.CheckMarkButton.String() + " " + currency
+		}
+		row = append(row, telegram.InlineKeyboardButton{Text: text, CallbackData: settingsCallbackPrefix + "cur:" + currency})
+		if len(row) == 3 || i == len(settingsCurrencies)-1 {
+			keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, row)
+			row = nil
+		}
+	}
+
+	for _, impact := range settingsImpacts {
+		text := GetEmojiSemaphore(impact) + " " + impact + "+"
+		if recipientMinImpact(recipient) == impact {
+			text = emoji.CheckMarkButton.String() + " " + text
+		}
+		row = append(row, telegram.InlineKeyboardButton{Text: text, CallbackData: settingsCallbackPrefix + "imp:" + impact})
+	}
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, row)
+	row = nil
+
+	for _, deliveryTime := range settingsDeliveryTimes {
+		text := formatDeliveryTime(deliveryTime)
+		if recipient.DeliveryTime == deliveryTime {
+			text = emoji.CheckMarkButton.String() + " " + text
+		}
+		row = append(row, telegram.InlineKeyboardButton{Text: text, CallbackData: settingsCallbackPrefix + "time:" + deliveryTime})
+	}
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, row)
+
+	return keyboard
+}
+
+// GetChatMember returns the membership of a user in a chat.
+func (s service) GetChatMember(chatId int, userId int) (telegram.ChatMember, error) {
+	var member telegram.ChatMember
+	err := s.callTelegram(context.Background(), "/getChatMember",
+		url.Values{
+			"chat_id": {strconv.Itoa(chatId)},
+			"user_id": {strconv.Itoa(userId)},
+		}, &member)
+	return member, err
+}
+
+// settingsDenied tells why a user cannot change the settings of a chat, or returns an empty text when allowed.
+// In groups only the administrators can change them.
+func settingsDenied(service Service, chat telegram.Chat, userId int) string {
+	if chat.Type != "group" && chat.Type != "supergroup" {
+		return ""
+	}
+	member, err := service.GetChatMember(chat.Id, userId)
+	if err != nil {
+		log.Printf("could not get chat member %d of chat %d: %s", userId, chat.Id, err.Error())
+		return "Could not check your permissions, try again later"
+	}
+	if !member.IsAdmin() {
+		return "Only the group administrators can change the settings"
+	}
+	return ""
+}
+
+// HandleSettingsCallback applies the setting of a pressed button and edits the menu in place.
+// In groups only the administrators can change the settings.
+// The returned text is shown to the user as the callback answer.
+func (s service) HandleSettingsCallback(query telegram.CallbackQuery) string {
+	setting, value, _ := strings.Cut(strings.TrimPrefix(query.Data, settingsCallbackPrefix), ":")
+	message := query.Message
+
+	if denied := settingsDenied(s, message.Chat, query.From.Id); denied != "" {
+		return denied
+	}
+
+	var invalid string
+	recipient, err := s.UpdateRecipient(message.Chat.Id, message.MessageThreadId, func(recipient *telegram.Recipient) {
+		switch setting {
+		case "cur":
+			currencies := toggleCurrency(recipientCurrencies(*recipient), value)
+			if len(currencies) == 0 {
+				invalid = "Keep at least one currency"
+				return
+			}
+			recipient.Currencies = currencies
+		case "imp":
+			if impact, ok := parseImpact(value); ok {
+				recipient.MinImpact = impact
+			}
+		case "time":
+			recipient.DeliveryTime = value
+		}
+	})
+	if errors.Is(err, ErrRecipientNotFound) {
+		return "This chat is not subscribed, use /subscribe first"
+	}
+	if err != nil {
+		log.Printf("could not update recipient %d: %s", message.Chat.Id, err.Error())
+		return "Could not save the settings, try again later"
+	}
+	if invalid != "" {
+		return invalid
+	}
+
+	telegramResponseBody, err := s.EditTelegramMessage(message.Chat.Id, message.MessageId,
+		s.PrepareSettingsMessage(recipient), s.PrepareSettingsKeyboard(recipient))
+	if err != nil {
+		log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
+	}
+	return "Saved"
+}
+
+// toggleCurrency adds currency when missing from currencies, removes it otherwise.
+func toggleCurrency(currencies []string, currency string) []string {
+	var toggled []string
+	found := false
+	for _, c := range currencies {
+		if strings.EqualFold(c, currency) {
+			found = true
+			continue
+		}
+		toggled = append(toggled, c)
+	}
+	if !found {
+		toggled = append(toggled, currency)
+	}
+	return toggled
+}
@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"bot/entity"
+	"github.com/enescakir/emoji"
+	"github.com/go-co-op/gocron"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSurprisePollMinutes    = 5
+	defaultSurpriseTimeoutMinutes = 120
+)
+
+// surpriseTracker remembers the released events still waiting for their actual value
+// and the ones already notified or given up on. The latter are kept in the history store, when enabled,
+// so a restart does not alert them again.
+type surpriseTracker struct {
+	mu sync.Mutex
+	// pending events by key, with the time they were first seen without actual
+	pending map[string]time.Time
+	// events by key already notified or expired
+	done    map[string]time.Time
+	history *HistoryStore
+}
+
+func newSurpriseTracker(history *HistoryStore) *surpriseTracker {
+	done, err := history.Alerted()
+	if err != nil {
+		log.Printf("could not read alerted events %s", err.Error())
+		done = map[string]time.Time{}
+	}
+	return &surpriseTracker{
+		pending: map[string]time.Time{},
+		done:    done,
+		history: history,
+	}
+}
+
+// releasedEvents returns the events to notify among the ones already released, and keeps track of the others
+// until timeout after their scheduled time.
+func (t *surpriseTracker) releasedEvents(events []entity.CalendarEvent, now time.Time, timeout time.Duration) []entity.CalendarEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var released []entity.CalendarEvent
+	alerted := map[string]time.Time{}
+	for _, e := range events {
+		key := e.Key()
+		if _, ok := t.done[key]; ok {
+			continue
+		}
+		date, err := e.Time()
+		if err != nil || date.After(now) {
+			continue
+		}
+		if e.Actual != nil {
+			released = append(released, e)
+			delete(t.pending, key)
+			t.done[key] = now
+			alerted[key] = now
+			continue
+		}
+		if now.Sub(date) > timeout {
+			log.Printf("no actual value for %s after %s, stop polling", key, timeout)
+			delete(t.pending, key)
+			t.done[key] = now
+			alerted[key] = now
+			continue
+		}
+		if _, ok := t.pending[key]; !ok {
+			log.Printf("waiting for actual value of %s", key)
+			t.pending[key] = now
+		}
+	}
+
+	// forget what is older than the polled window
+	var forgotten []string
+	for key, at := range t.done {
+		if now.Sub(at) > 2*timeout+24*time.Hour {
+			delete(t.done, key)
+			forgotten = append(forgotten, key)
+		}
+	}
+	if err := t.history.SaveAlerted(alerted, forgotten); err != nil {
+		log.Printf("could not save alerted events %s", err.Error())
+	}
+	return released
+}
+
+func (s service) ScheduledSurpriseNotification() {
+	pollMinutes := s.config.SurprisePollMinutes
+	if pollMinutes <= 0 {
+		pollMinutes = defaultSurprisePollMinutes
+	}
+	timeoutMinutes := s.config.SurpriseTimeoutMinutes
+	if timeoutMinutes <= 0 {
+		timeoutMinutes = defaultSurpriseTimeoutMinutes
+	}
+	timeout := time.Duration(timeoutMinutes) * time.Minute
+
+	s1 := gocron.NewScheduler(time.UTC)
+	_, err := s1.Every(pollMinutes).Minutes().Do(func() {
+		now := time.Now().UTC()
+
+		events, err := s.provider.GetEvents(now.Add(-timeout), now)
+		if err != nil {
+			log.Printf("got error when calling Economic Calendar API %s", err.Error())
+			return
+		}
+
+		if err := s.history.Save(events); err != nil {
+			log.Printf("could not save events history %s", err.Error())
+		}
+
+		relevant := acceptedByAny(s.recipients.All(), events)
+
+		for _, e := range s.surprises.releasedEvents(relevant, now, timeout) {
+			for _, recipient := range s.recipients.All() {
+				if !acceptsEvent(recipient, e) {
+					continue
+				}
+				message := s.PrepareSurpriseMessage(e, loadLocation(recipient.Timezone))
+				log.Printf("send to chatId, %s", strconv.Itoa(recipient.ChatId))
+				_, telegramResponseBody, err := s.SendTextToTelegramChat(recipient.ChatId, recipient.MessageThreadId, message, "")
+				if err != nil {
+					log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
+				} else {
+					log.Printf("surprise alert successfully distributed to chat id %d", recipient.ChatId)
+				}
+			}
+		}
+	})
+	s1.StartAsync()
+	if err != nil {
+		log.Printf("error creating job: %v", err)
+	}
+	_, t := s1.NextRun()
+	log.Printf("next run at: %s", t)
+}
+
+// PrepareSurpriseMessage renders a released event, its time in loc, the timezone of the recipient.
+func (s service) PrepareSurpriseMessage(e entity.CalendarEvent, loc *time.Location) string {
+	if bank, ok := rateDecisionBank(e); ok {
+		return emoji.HighVoltage.String() + " RELEASED\n\n" + s.PrepareRateDecisionMessage(bank, e, loc)
+	}
+	return emoji.HighVoltage.String() + " RELEASED: " + e.Event + "  " + GetEmojiCountry(e.Country) + "\n\n" +
+		emoji.CurrencyExchange.String() + "  CURRENCY: " + e.Currency + "\n" +
+		emoji.BarChart.String() + "  ACTUAL: " + FormatEventValue(e.Actual, e.Unit) + "\n" +
+		emoji.CrystalBall.String() + "  FORECAST: " + FormatEventValue(e.Forecast, e.Unit) + "\n" +
+		emoji.HourglassDone.String() + "  PREVIOUS: " + FormatEventValue(e.Previous, e.Unit) + "\n\n" +
+		surpriseVerdict(e.Actual, e.Forecast, e.Unit)
+}
+
+// surpriseVerdict compares the actual value with the forecast, reporting the surprise in absolute and relative terms.
+func surpriseVerdict(actual *float64, forecast *float64, unit string) string {
+	if actual == nil || forecast == nil {
+		return emoji.BalanceScale.String() + " No forecast to compare with"
+	}
+
+	surprise := *actual - *forecast
+	magnitude := strconv.FormatFloat(surprise, 'f', -1, 64) + unit
+	if surprise > 0 {
+		magnitude = "+" + magnitude
+	}
+	if *forecast != 0 {
+		percent := surprise / math.Abs(*forecast) * 100
+		magnitude = magnitude + " (" + strconv.FormatFloat(percent, 'f', 1, 64) + "%)"
+	}
+
+	switch {
+	case math.Abs(surprise) < 1e-9:
+		return emoji.BalanceScale.String() + " IN LINE with forecast"
+	case surprise > 0:
+		return emoji.ChartIncreasing.String() + " BEAT by " + magnitude
+	default:
+		return emoji.ChartDecreasing.String() + " MISS by " + magnitude
+	}
+}
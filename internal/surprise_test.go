@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"bot/entity"
+	"github.com/enescakir/emoji"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrepareSurpriseMessageTimezone(t *testing.T) {
+	s := service{}
+	rome, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Skipf("no timezone database: %v", err)
+	}
+	decision := entity.CalendarEvent{Date: "2025-03-06 13:15:00", Country: "EU", Event: "Main Refinancing Rate", Currency: "EUR",
+		Actual: float(2.65), Forecast: float(2.65), Previous: float(2.9), Unit: "%"}
+
+	tests := []struct {
+		loc  *time.Location
+		want string
+	}{
+		{time.UTC, "2025-03-06 13:15 UTC"},
+		{rome, "2025-03-06 14:15 CET"},
+	}
+	for _, tt := range tests {
+		message := s.PrepareSurpriseMessage(decision, tt.loc)
+		if !strings.Contains(message, tt.want) {
+			t.Errorf("PrepareSurpriseMessage in %s = %q, want date %q", tt.loc, message, tt.want)
+		}
+	}
+}
+
+func TestSurpriseVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		actual   *float64
+		forecast *float64
+		unit     string
+		want     string
+	}{
+		{"no actual", nil, float(0.3), "%", emoji.BalanceScale.String() + " No forecast to compare with"},
+		{"no forecast", float(0.3), nil, "%", emoji.BalanceScale.String() + " No forecast to compare with"},
+		{"in line", float(0.3), float(0.3), "%", emoji.BalanceScale.String() + " IN LINE with forecast"},
+		{"beat", float(0.5), float(0.25), "%", emoji.ChartIncreasing.String() + " BEAT by +0.25% (100.0%)"},
+		{"miss", float(180), float(200), "K", emoji.ChartDecreasing.String() + " MISS by -20K (-10.0%)"},
+		{"negative forecast", float(-0.1), float(-0.2), "%", emoji.ChartIncreasing.String() + " BEAT by +0.1% (50.0%)"},
+		{"zero forecast", float(0.2), float(0), "%", emoji.ChartIncreasing.String() + " BEAT by +0.2%"},
+	}
+	for _, tt := range tests {
+		if got := surpriseVerdict(tt.actual, tt.forecast, tt.unit); got != tt.want {
+			t.Errorf("%s: surpriseVerdict = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSurpriseTrackerReleasedEvents(t *testing.T) {
+	now := time.Date(2025, 3, 5, 14, 0, 0, 0, time.UTC)
+	timeout := 2 * time.Hour
+	released := entity.CalendarEvent{Date: "2025-03-05 13:30:00", Event: "CPI m/m", Currency: "USD", Actual: float(0.4)}
+	pending := entity.CalendarEvent{Date: "2025-03-05 13:45:00", Event: "Industrial Production m/m", Currency: "USD"}
+	expired := entity.CalendarEvent{Date: "2025-03-05 11:00:00", Event: "MBA Mortgage Applications", Currency: "USD"}
+	upcoming := entity.CalendarEvent{Date: "2025-03-05 15:00:00", Event: "ISM Services PMI", Currency: "USD", Actual: float(53)}
+	events := []entity.CalendarEvent{released, pending, expired, upcoming}
+
+	tracker := newSurpriseTracker(nil)
+	got := tracker.releasedEvents(events, now, timeout)
+	if len(got) != 1 || got[0].Key() != released.Key() {
+		t.Fatalf("first poll released %v, want only %s", got, released.Event)
+	}
+	if _, ok := tracker.pending[pending.Key()]; !ok {
+		t.Errorf("%s not pending", pending.Event)
+	}
+	if _, ok := tracker.done[expired.Key()]; !ok {
+		t.Errorf("%s not given up after the timeout", expired.Event)
+	}
+
+	// the pending event gets its actual value, the released one is not alerted again
+	pending.Actual = float(0.1)
+	got = tracker.releasedEvents([]entity.CalendarEvent{released, pending, expired, upcoming}, now.Add(5*time.Minute), timeout)
+	if len(got) != 1 || got[0].Key() != pending.Key() {
+		t.Errorf("second poll released %v, want only %s", got, pending.Event)
+	}
+}
+
+func TestSurpriseTrackerPersistsAlerted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	now := time.Date(2025, 3, 5, 14, 0, 0, 0, time.UTC)
+	released := entity.CalendarEvent{Date: "2025-03-05 13:30:00", Event: "CPI m/m", Currency: "USD", Actual: float(0.4)}
+
+	history, err := OpenHistoryStore(path)
+	if err != nil {
+		t.Fatalf("OpenHistoryStore: %v", err)
+	}
+	if got := newSurpriseTracker(history).releasedEvents([]entity.CalendarEvent{released}, now, 2*time.Hour); len(got) != 1 {
+		t.Fatalf("released %v, want %s", got, released.Event)
+	}
+	_ = history.Close()
+
+	// after a restart the event is not alerted again
+	history = openTestHistoryStore(t, path)
+	if got := newSurpriseTracker(history).releasedEvents([]entity.CalendarEvent{released}, now.Add(5*time.Minute), 2*time.Hour); len(got) != 0 {
+		t.Errorf("released %v after a restart, want none", got)
+	}
+
+	// and it is forgotten once out of the polled window
+	later := now.Add(30 * time.Hour)
+	newSurpriseTracker(history).releasedEvents(nil, later, 2*time.Hour)
+	alerted, err := history.Alerted()
+	if err != nil {
+		t.Fatalf("Alerted: %v", err)
+	}
+	if len(alerted) != 0 {
+		t.Errorf("alerted = %v, want none after the window", alerted)
+	}
+}
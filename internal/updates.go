@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// how long an update id is remembered when no window is configured
+const defaultUpdateDedupMinutes = 10
+
+// updateDeduplicator remembers the update ids received within a window, so an update replayed
+// or delivered twice by Telegram is dispatched once.
+type updateDeduplicator struct {
+	mu     sync.Mutex
+	window time.Duration
+	seen   map[int]time.Time
+}
+
+func newUpdateDeduplicator(window time.Duration) *updateDeduplicator {
+	if window <= 0 {
+		window = defaultUpdateDedupMinutes * time.Minute
+	}
+	return &updateDeduplicator{window: window, seen: map[int]time.Time{}}
+}
+
+// firstSeen records updateId and tells whether it was not received within the window before now.
+func (d *updateDeduplicator) firstSeen(updateId int, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, at := range d.seen {
+		if now.Sub(at) > d.window {
+			delete(d.seen, id)
+		}
+	}
+	if _, ok := d.seen[updateId]; ok {
+		return false
+	}
+	d.seen[updateId] = now
+	return true
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"bot/conf"
+	"bot/entity/telegram"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// the update types dispatched by dispatchUpdate, the others are not sent by Telegram
+var allowedUpdates = []string{"message", "callback_query", "inline_query"}
+
+// SetWebhook makes Telegram send the updates to webhookUrl, with secretToken in the X-Telegram-Bot-Api-Secret-Token header.
+// A maxConnections of zero keeps the Telegram default.
+func (s service) SetWebhook(ctx context.Context, webhookUrl string, secretToken string, maxConnections int) error {
+	encodedAllowedUpdates, err := json.Marshal(allowedUpdates)
+	if err != nil {
+		return err
+	}
+	values := url.Values{
+		"url":             {webhookUrl},
+		"allowed_updates": {string(encodedAllowedUpdates)},
+	}
+	if secretToken != "" {
+		values.Set("secret_token", secretToken)
+	}
+	if maxConnections > 0 {
+		values.Set("max_connections", strconv.Itoa(maxConnections))
+	}
+	log.Printf("Setting webhook %s", webhookUrl)
+	return s.callTelegram(ctx, "/setWebhook", values, nil)
+}
+
+func (s service) GetWebhookInfo(ctx context.Context) (telegram.WebhookInfo, error) {
+	var info telegram.WebhookInfo
+	err := s.callTelegram(ctx, "/getWebhookInfo", url.Values{}, &info)
+	return info, err
+}
+
+// DeleteWebhook stops the webhook, so updates can be received with getUpdates. The pending updates are kept.
+func (s service) DeleteWebhook(ctx context.Context) error {
+	log.Printf("Deleting webhook")
+	return s.callTelegram(ctx, "/deleteWebhook", url.Values{}, nil)
+}
+
+// RegisterWebhook sets the webhook of the config and checks it with getWebhookInfo, logging its status.
+func RegisterWebhook(ctx context.Context, service Service, config conf.Config) error {
+	err := service.SetWebhook(ctx, config.WebhookUrl, config.WebhookSecretToken, config.WebhookMaxConnections)
+	if err != nil {
+		return err
+	}
+
+	info, err := service.GetWebhookInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if info.Url != config.WebhookUrl {
+		return fmt.Errorf("webhook is %q instead of %q", info.Url, config.WebhookUrl)
+	}
+	log.Printf("webhook %s registered, %d pending updates", info.Url, info.PendingUpdateCount)
+	if info.LastErrorDate != 0 {
+		log.Printf("last webhook error at %s: %s",
+			time.Unix(info.LastErrorDate, 0).UTC().Format(time.RFC3339), info.LastErrorMessage)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"bot/entity"
+	"bot/entity/telegram"
+	"github.com/enescakir/emoji"
+	"github.com/go-co-op/gocron"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultWeeklyOutlookTime = "18:00"
+
+func (s service) ScheduledWeeklyOutlook() {
+	at := s.config.WeeklyOutlookTime
+	if at == "" {
+		at = defaultWeeklyOutlookTime
+	}
+
+	s1 := gocron.NewScheduler(time.UTC)
+	_, err := s1.Every(1).Sunday().At(at).Do(func() {
+		now := time.Now()
+		// the range covers the coming week in every timezone
+		events, err := s.GetEconomicCalendar(now, now.AddDate(0, 0, 7))
+		if err != nil {
+			log.Printf("got error when calling Economic Calendar API %s", err.Error())
+			return
+		}
+
+		// recipients sharing timezone and filter get the same message
+		messages := map[string]string{}
+		for _, recipient := range s.recipients.All() {
+			message, ok := messages[filterKey(recipient)]
+			if !ok {
+				monday := nextMonday(now, loadLocation(recipient.Timezone))
+				message = s.PrepareWeeklyOutlookMessage(recipient, monday, events)
+				messages[filterKey(recipient)] = message
+			}
+
+			log.Printf("send to chatId, %s", strconv.Itoa(recipient.ChatId))
+			_, telegramResponseBody, err := s.SendTextToTelegramChat(recipient.ChatId, recipient.MessageThreadId, message, "")
+			if err != nil {
+				log.Printf("got error %s from telegram, response body is %s", err.Error(), telegramResponseBody)
+			} else {
+				log.Printf("weekly outlook successfully distributed to chat id %d", recipient.ChatId)
+			}
+		}
+	})
+	s1.StartAsync()
+	if err != nil {
+		log.Printf("error creating job: %v", err)
+	}
+	_, t := s1.NextRun()
+	log.Printf("next run at: %s", t)
+}
+
+// nextMonday returns the start of the Monday following now in the given location.
+func nextMonday(now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	days := (8 - int(local.Weekday())) % 7
+	if days == 0 {
+		days = 7
+	}
+	return time.Date(local.Year(), local.Month(), local.Day()+days, 0, 0, 0, 0, loc)
+}
+
+// PrepareWeeklyOutlookMessage lists the events from Monday to Friday passing the recipient filter, grouped by day,
+// followed by the high and medium impact events count per currency and the busiest day.
+func (s service) PrepareWeeklyOutlookMessage(recipient telegram.Recipient, monday time.Time, events []entity.CalendarEvent) string {
+	loc := monday.Location()
+	friday := monday.AddDate(0, 0, 4)
+	message := emoji.SpiralCalendar.String() + " Weekly outlook " + monday.Format("2006-01-02") + " - " + friday.Format("2006-01-02") + "\n\n"
+
+	// high and medium counts per currency, index 0 high and 1 medium
+	counts := map[string]*[2]int{}
+	busiestDay := ""
+	busiestCount := 0
+
+	for day := 0; day < 5; day++ {
+		start := monday.AddDate(0, 0, day)
+		end := start.AddDate(0, 0, 1)
+		message = message + strings.ToUpper(start.Weekday().String()) + " " + start.Format("02/01") + "\n"
+
+		dayCount := 0
+		listed := 0
+		for _, e := range events {
+			date, err := e.Time()
+			if err != nil || date.Before(start) || !date.Before(end) || !acceptsCurrency(recipient, e.Currency) {
+				continue
+			}
+			if e.Impact == "High" || e.Impact == "Medium" {
+				count, ok := counts[e.Currency]
+				if !ok {
+					count = &[2]int{}
+					counts[e.Currency] = count
+				}
+				if e.Impact == "High" {
+					count[0]++
+				} else {
+					count[1]++
+				}
+				dayCount++
+			}
+			if acceptsEvent(recipient, e) {
+				message = message + "  " + date.In(loc).Format("15:04") + " " + GetEmojiCountry(e.Country) + " " +
+					e.Currency + " " + e.Event + " " + GetEmojiSemaphore(e.Impact) + "\n"
+				listed++
+			}
+		}
+		if listed == 0 {
+			message = message + "  Nessuna Notizia Rilevante\n"
+		}
+		message = message + "\n"
+
+		if dayCount > busiestCount {
+			busiestDay = start.Weekday().String()
+			busiestCount = dayCount
+		}
+	}
+
+	currencies := make([]string, 0, len(counts))
+	for currency := range counts {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	message = message + emoji.BarChart.String() + " High / Medium events per currency:\n"
+	if len(currencies) == 0 {
+		message = message + "  -\n"
+	}
+	for _, currency := range currencies {
+		message = message + "  " + currency + ": " + strconv.Itoa(counts[currency][0]) + " " + GetEmojiSemaphore("High") +
+			" / " + strconv.Itoa(counts[currency][1]) + " " + GetEmojiSemaphore("Medium") + "\n"
+	}
+
+	if busiestCount > 0 {
+		message = message + "\n" + emoji.Fire.String() + " Busiest day: " + busiestDay + " (" + strconv.Itoa(busiestCount) + " events)"
+	}
+	return message
+}
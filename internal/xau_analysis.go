@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"github.com/enescakir/emoji"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xauCandle is a daily XAUUSD row of the sheet: date, close, open, high, low.
+type xauCandle struct {
+	Date  time.Time
+	Close float64
+	Open  float64
+	High  float64
+	Low   float64
+}
+
+// xauReaction summarizes a set of daily candles.
+type xauReaction struct {
+	Days int
+	// average high-low range, in dollars and in percent of the open
+	Range        float64
+	RangePercent float64
+	// share of days closing above the open and average close-open move in percent
+	UpPercent   float64
+	MovePercent float64
+}
+
+// readXauCandles reads the daily XAUUSD history kept in the sheet by ScheduledXauSheetUpdate.
+func (s service) readXauCandles() ([]xauCandle, error) {
+	readRange := s.config.XauHistoryRange
+	if readRange == "" {
+		readRange = s.config.ReadRange
+	}
+	resp, err := s.sheets.Spreadsheets.Values.Get(s.config.SpreadsheetId, readRange).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	var candles []xauCandle
+	for i, row := range resp.Values {
+		// header row, and rows without high and low
+		if i == 0 || len(row) < 5 {
+			continue
+		}
+		date, err := time.Parse("02/01/2006", sheetCell(row[0]))
+		if err != nil {
+			log.Printf("Unable to parse date %v: %v", row[0], err)
+			continue
+		}
+		candles = append(candles, xauCandle{
+			Date:  date,
+			Close: parseSheetNumber(sheetCell(row[1])),
+			Open:  parseSheetNumber(sheetCell(row[2])),
+			High:  parseSheetNumber(sheetCell(row[3])),
+			Low:   parseSheetNumber(sheetCell(row[4])),
+		})
+	}
+	return candles, nil
+}
+
+func sheetCell(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	return ""
+}
+
+func newXauReaction(candles []xauCandle) xauReaction {
+	reaction := xauReaction{}
+	up := 0
+	for _, c := range candles {
+		if c.Open <= 0 {
+			continue
+		}
+		reaction.Days++
+		reaction.Range += c.High - c.Low
+		reaction.RangePercent += (c.High - c.Low) / c.Open * 100
+		reaction.MovePercent += (c.Close - c.Open) / c.Open * 100
+		if c.Close > c.Open {
+			up++
+		}
+	}
+	if reaction.Days > 0 {
+		reaction.Range /= float64(reaction.Days)
+		reaction.RangePercent /= float64(reaction.Days)
+		reaction.MovePercent /= float64(reaction.Days)
+		reaction.UpPercent = float64(up) / float64(reaction.Days) * 100
+	}
+	return reaction
+}
+
+// PrepareXauImpactMessage compares the XAUUSD daily range and direction on the release days of the indicators
+// matching query, taken from the events history, with the other days of the sheet.
+func (s service) PrepareXauImpactMessage(query string) string {
+	if s.history == nil {
+		return emoji.CrossMark.String() + " The events history is not enabled"
+	}
+	events, err := s.history.Releases(query)
+	if err != nil {
+		log.Printf("could not read events history %s", err.Error())
+		return emoji.CrossMark.String() + " Could not read the events history, try again later"
+	}
+
+	releaseDays := map[string]bool{}
+	indicators := map[string]bool{}
+	for _, e := range events {
+		if e.Actual == nil || len(e.Date) < len("2006-01-02") {
+			continue
+		}
+		releaseDays[e.Date[:len("2006-01-02")]] = true
+		indicators[e.Event+" ("+e.Currency+")"] = true
+	}
+	if len(releaseDays) == 0 {
+		return emoji.CrossMark.String() + " No released values for " + query
+	}
+
+	candles, err := s.readXauCandles()
+	if err != nil {
+		log.Printf("Unable to retrieve data from sheet: %v", err)
+		return emoji.CrossMark.String() + " Could not read the XAUUSD history, try again later"
+	}
+
+	var onRelease, otherDays []xauCandle
+	for _, c := range candles {
+		if releaseDays[c.Date.Format("2006-01-02")] {
+			onRelease = append(onRelease, c)
+		} else {
+			otherDays = append(otherDays, c)
+		}
+	}
+	if len(onRelease) == 0 {
+		return emoji.CrossMark.String() + " No XAUUSD data on the release days of " + query
+	}
+
+	names := make([]string, 0, len(indicators))
+	for name := range indicators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	release := newXauReaction(onRelease)
+	other := newXauReaction(otherDays)
+	message := emoji.Butter.String() + " XAUUSD reaction to " + strings.Join(names, ", ") + "\n\n" +
+		emoji.HighVoltage.String() + " " + formatXauReaction("Release days", release) +
+		emoji.Calendar.String() + " " + formatXauReaction("Other days", other)
+	if other.Range > 0 {
+		message = message + emoji.BarChart.String() + " Range on release days: " +
+			strconv.FormatFloat(release.Range/other.Range, 'f', 2, 64) + "x the other days"
+	}
+	return message
+}
+
+func formatXauReaction(label string, reaction xauReaction) string {
+	move := strconv.FormatFloat(reaction.MovePercent, 'f', 2, 64) + "%"
+	if reaction.MovePercent > 0 {
+		move = "+" + move
+	}
+	return label + " (" + strconv.Itoa(reaction.Days) + "):\n" +
+		"  avg range " + strconv.FormatFloat(reaction.Range, 'f', 2, 64) +
+		" (" + strconv.FormatFloat(reaction.RangePercent, 'f', 2, 64) + "%)\n" +
+		"  up days " + strconv.FormatFloat(reaction.UpPercent, 'f', 0, 64) + "%, avg move " + move + "\n\n"
+}
@@ -4,6 +4,7 @@ import (
 	"bot/conf"
 	"bot/internal"
 	"context"
+	"errors"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	"golang.org/x/oauth2/google"
@@ -12,6 +13,10 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -20,12 +25,55 @@ func main() {
 	if err != nil {
 		log.Fatalf("could not decode config %s\n", err.Error())
 	}
+	if cfg.UpdatesMode != "" && cfg.UpdatesMode != internal.UpdatesModeWebhook && cfg.UpdatesMode != internal.UpdatesModePolling {
+		log.Fatalf("unknown updates mode %s\n", cfg.UpdatesMode)
+	}
+
+	if cfg.ParseMode != "" && cfg.ParseMode != internal.ParseModeHTML && cfg.ParseMode != internal.ParseModeMarkdownV2 {
+		log.Fatalf("unknown parse mode %s\n", cfg.ParseMode)
+	}
 
-	recipients, err := conf.LoadRecipients(cfg.RecipientsFile)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	recipients, err := internal.NewRecipientStore(cfg.RecipientsFile)
 	if err != nil {
 		log.Fatalf("could not decode recipients %s\n", err.Error())
 	}
 
+	holidays := internal.NewHolidayCalendar(nil)
+	if cfg.HolidaysFile != "" {
+		marketHolidays, err := conf.LoadHolidays(cfg.HolidaysFile)
+		if err != nil {
+			log.Fatalf("could not decode holidays %s\n", err.Error())
+		}
+		holidays = internal.NewHolidayCalendar(marketHolidays)
+		year := time.Now().Year()
+		if missing := holidays.MissingYear(year); len(missing) > 0 {
+			log.Printf("warning: %s has no %d holidays for %s, update it to keep the market closures\n",
+				cfg.HolidaysFile, year, strings.Join(missing, ", "))
+		}
+	}
+
+	var history *internal.HistoryStore
+	if cfg.HistoryFile != "" {
+		history, err = internal.OpenHistoryStore(cfg.HistoryFile)
+		if err != nil {
+			log.Fatalf("could not open events history %s\n", err.Error())
+		}
+		defer func(history *internal.HistoryStore) {
+			err := history.Close()
+			if err != nil {
+				log.Printf("could not close events history %s\n", err.Error())
+			}
+		}(history)
+	}
+
+	provider, err := internal.NewCalendarProvider(cfg)
+	if err != nil {
+		log.Fatalf("could not create calendar provider %s\n", err.Error())
+	}
+
 	var sheetsService *sheets.Service
 
 	creeds, err := os.ReadFile(cfg.KeyFile)
@@ -49,27 +97,74 @@ func main() {
 		port = cfg.Port
 	}
 
+	scheduler := internal.NewService(cfg, provider, recipients, holidays, history, sheetsService)
+
+	commands := internal.NewCommandRegistry(cfg.BotUsername)
+	if cfg.CommandDescriptionsFile != "" {
+		descriptions, err := conf.LoadCommandDescriptions(cfg.CommandDescriptionsFile)
+		if err != nil {
+			log.Fatalf("could not decode command descriptions %s\n", err.Error())
+		}
+		commands.Localize(descriptions)
+	}
+	commands.Publish(scheduler, cfg.CommandLanguages)
+
 	server := &http.Server{
 		Addr:    cfg.Address + ":" + port,
-		Handler: buildHandler(cfg),
+		Handler: buildHandler(scheduler, commands, cfg),
 	}
 
-	scheduler := internal.NewService(cfg)
-	scheduler.Readyz(recipients)
+	scheduler.Readyz()
 	//CALENDAR NEWS SCHEDULER
-	scheduler.ScheduledNewsNotification(recipients)
+	scheduler.ScheduledNewsNotification()
+	scheduler.ScheduledSurpriseNotification()
+	scheduler.ScheduledCalendarRefresh()
+	scheduler.ScheduledWeeklyOutlook()
 
 	//XAU SCHEDULER
-	scheduler.ScheduledXauNotification(recipients, cfg.SpreadsheetId, cfg.ReadRange, sheetsService)
-	scheduler.ScheduledXauSheetUpdate(recipients, cfg.SpreadsheetId, cfg.WriteRange, cfg.SheetId, cfg.FinancialModelingPrepUrl, sheetsService)
+	scheduler.ScheduledXauNotification(cfg.SpreadsheetId, cfg.ReadRange, sheetsService)
+	scheduler.ScheduledXauSheetUpdate(cfg.SpreadsheetId, cfg.WriteRange, cfg.SheetId, cfg.FinancialModelingPrepUrl, sheetsService)
+
+	if cfg.UpdatesMode == internal.UpdatesModePolling {
+		// getUpdates is refused while a webhook is set
+		if err := scheduler.DeleteWebhook(ctx); err != nil {
+			log.Printf("could not delete webhook %s\n", err.Error())
+		}
+		log.Println("Polling updates")
+		internal.PollUpdates(ctx, scheduler, commands, cfg.PollingTimeoutSeconds)
+		return
+	}
 
-	log.Println("Listening ", server.Addr)
-	err = server.ListenAndServe()
-	log.Fatalln(err)
+	if cfg.WebhookUrl != "" {
+		if err := internal.RegisterWebhook(ctx, scheduler, cfg); err != nil {
+			log.Printf("could not register webhook %s\n", err.Error())
+		}
+	}
+
+	go func() {
+		log.Println("Listening ", server.Addr)
+		err := server.ListenAndServe()
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalln(err)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("could not shut down the server %s\n", err.Error())
+	}
+	log.Println("Server stopped")
 
+	if cfg.WebhookUrl != "" && cfg.DeleteWebhookOnShutdown {
+		if err := scheduler.DeleteWebhook(shutdownCtx); err != nil {
+			log.Printf("could not delete webhook %s\n", err.Error())
+		}
+	}
 }
 
-func buildHandler(cfg conf.Config) http.Handler {
+func buildHandler(service internal.Service, commands *internal.CommandRegistry, cfg conf.Config) http.Handler {
 
 	//all APIs are under "/api/v1" path prefix
 	router := mux.NewRouter()
@@ -83,7 +178,7 @@ func buildHandler(cfg conf.Config) http.Handler {
 	})
 
 	routerGroup := router.PathPrefix("/api/v1").Subrouter()
-	internal.RegisterHandlers(routerGroup, internal.NewService(cfg))
+	internal.RegisterHandlers(routerGroup, service, commands, cfg)
 	handler := c.Handler(router)
 	return handler
 }